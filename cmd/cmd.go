@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"fincli/internal/camt"
+	"fincli/internal/csvstatement"
 	"fincli/internal/iostreams"
+	"fincli/internal/jsonstatement"
+	"fincli/internal/ledger"
+	"fincli/internal/mt940"
+	"fincli/internal/ofx"
+	"fincli/internal/qif"
+	"fincli/internal/statement"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -22,17 +31,22 @@ func Main() exitCode {
 		Out: os.Stdout,
 		Err: os.Stderr,
 	}
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(func() { initConfig(io) })
 	rootCmd := NewCmdRoot(io)
-	err := rootCmd.Execute()
-	if err != nil {
-		fmt.Println(err)
+	rootCmd.SetOut(io.Out)
+	rootCmd.SetErr(io.Err)
+	// The error is printed below, once, without the usage text.
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(io.Err, err)
+		return exitError
 	}
-	return exitError
+	return exitOK
 }
 
 // initConfig reads in config file and ENV variables if set.
-func initConfig() {
+func initConfig(io *iostreams.IOStreams) {
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -51,6 +65,112 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(io.Err, "Using config file:", viper.ConfigFileUsed())
+	}
+}
+
+// newFormatRegistry returns the registry of known formats. Unless init is
+// given, it holds the built-in layouts and the user's own layouts.
+func newFormatRegistry(init *csvstatement.FormatRegistry) (*csvstatement.FormatRegistry, error) {
+	return csvstatement.NewRegistry(&csvstatement.Factory{
+		InitRegistry: init,
+		LayoutDirs:   layoutDirs(),
+	})
+}
+
+// newStatementRegistry returns the registry of the statement formats that
+// are not CSV layouts. Unless init is given, it holds all built-in codecs.
+//
+// The order of day and month in QIF dates is set with the "qif_date_order"
+// config key, which is "mdy" (default) or "dmy". The asset account of the
+// Ledger, hledger and Beancount journals is set with "ledger_account", and the
+// commodity of transactions without a currency with "ledger_currency".
+func newStatementRegistry(init statement.Registry) (statement.Registry, error) {
+	if init != nil {
+		return init, nil
+	}
+	qifDateOrder, err := qif.ParseDateOrder(viper.GetString("qif_date_order"))
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerAccount := viper.GetString("ledger_account")
+	ledgerCurrency := viper.GetString("ledger_currency")
+
+	registry := statement.Registry{}
+	registry.Add(statement.Codec{
+		Id:          "camt053",
+		Description: "ISO 20022 camt.053 bank to customer account statement (XML)",
+		NewReader:   func() statement.Reader { return camt.NewReader(camt.Camt053) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "camt054",
+		Description: "ISO 20022 camt.054 bank to customer debit/credit notification (XML)",
+		NewReader:   func() statement.Reader { return camt.NewReader(camt.Camt054) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "beancount",
+		Description: "Beancount plain-text accounting journal",
+		NewWriter:   func() statement.Writer { return ledger.NewWriter(ledger.Beancount, ledgerAccount, ledgerCurrency) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "hledger",
+		Description: "hledger plain-text accounting journal",
+		NewWriter:   func() statement.Writer { return ledger.NewWriter(ledger.HLedger, ledgerAccount, ledgerCurrency) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "json",
+		Description: fmt.Sprintf("JSON array of transactions (schema version %d)", jsonstatement.Version),
+		NewReader:   func() statement.Reader { return jsonstatement.NewReader(false) },
+		NewWriter:   func() statement.Writer { return jsonstatement.NewWriter(false) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "ndjson",
+		Description: fmt.Sprintf("Newline-delimited JSON, one transaction per line (schema version %d)", jsonstatement.Version),
+		NewReader:   func() statement.Reader { return jsonstatement.NewReader(true) },
+		NewWriter:   func() statement.Writer { return jsonstatement.NewWriter(true) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "ledger",
+		Description: "Ledger plain-text accounting journal",
+		NewWriter:   func() statement.Writer { return ledger.NewWriter(ledger.Ledger, ledgerAccount, ledgerCurrency) },
+	})
+	registry.Add(statement.Codec{
+		Id:          "mt940",
+		Description: "SWIFT MT940 customer statement",
+		NewReader:   func() statement.Reader { return mt940.NewReader() },
+	})
+	registry.Add(statement.Codec{
+		Id:          "ofx",
+		Description: "Open Financial Exchange, read as OFX 1.x (SGML) or 2.x (XML) and written as OFX 2.2",
+		NewReader:   func() statement.Reader { return ofx.NewReader() },
+		NewWriter:   func() statement.Writer { return ofx.NewWriter() },
+	})
+	registry.Add(statement.Codec{
+		Id:          "qfx",
+		Description: "Quicken Web Connect, which is OFX with Intuit extensions",
+		NewReader:   func() statement.Reader { return ofx.NewReader() },
+		NewWriter:   func() statement.Writer { return ofx.NewWriter() },
+	})
+	registry.Add(statement.Codec{
+		Id:          "qif",
+		Description: fmt.Sprintf("Quicken Interchange Format bank records, with dates in %s order", qifDateOrder),
+		NewReader:   func() statement.Reader { return qif.NewReader(qifDateOrder) },
+		NewWriter:   func() statement.Writer { return qif.NewWriter(qifDateOrder) },
+	})
+	return registry, nil
+}
+
+// layoutDirs returns the directories with user layouts. The directory can be
+// set with the "layouts" config key, and defaults to fincli/layouts in the
+// user config directory (e.g. ~/.config/fincli/layouts).
+func layoutDirs() []string {
+	if dir := viper.GetString("layouts"); dir != "" {
+		return []string{dir}
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
 	}
+	return []string{filepath.Join(configDir, "fincli", "layouts")}
 }
@@ -1,46 +1,125 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fincli/internal/charset"
 	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
 	"fincli/internal/iostreams"
+	"fincli/internal/money"
+	"fincli/internal/rules"
+	"fincli/internal/statement"
 	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type ConvertOptions struct {
 	IO       *iostreams.IOStreams
 	Registry *csvstatement.FormatRegistry
+	Codecs   statement.Registry
 
-	FilePath   string
+	FilePaths  []string
 	FromFormat string
 	ToFormat   string
+	OnError    string
+	Output     string
+	Force      bool
+	Jobs       int
+
+	// OutputEncoding is the character encoding of the output, which replaces
+	// the encoding of the output format.
+	OutputEncoding string
+
+	// Account and BankId identify the account in formats that require them,
+	// such as OFX, when the statement does not tell.
+	Account string
+	BankId  string
+
+	// RulesPath is the YAML file with the rules that enrich the transactions,
+	// see package rules. It defaults to the "rules" config key, which is
+	// relative to the directory of the config file.
+	RulesPath string
 }
 
+// Values of the --on-error flag of convert.
+const (
+	onErrorFail    = "fail"
+	onErrorSkip    = "skip"
+	onErrorCollect = "collect"
+)
+
 func NewCmdConvert(io *iostreams.IOStreams, runF func(*ConvertOptions) error) *cobra.Command {
 	opts := &ConvertOptions{
 		IO: io,
 	}
 
 	cmd := &cobra.Command{
-		Use:   "convert [filepath]",
-		Short: "Convert a CSV bank statement to a different format",
-		Long: `Convert a CSV bank statement from one format to another.
+		Use:   "convert [<path>...]",
+		Short: "Convert bank statements to a different format",
+		Long: `Convert bank statements from one format to another.
+
+		The formats are the CSV layouts and the other statement formats, such as OFX, listed by 'fincli formats list'.
+
+		Provide the paths to the statement files as arguments. A path may be a glob pattern, such as 'statements/*.csv', or a directory, which stands for the files in it. The files are converted concurrently, by as many workers as set by --jobs.
+
+		To read a statement from standard input, as in 'curl ... | fincli convert - --from bulder --to ynab', use '-' as the path, or give no path at all when standard input is not a terminal.
+
+		The files should be formatted according to the format specified by the --from flag. If --from is omitted, the format of each file is detected from its first lines.
+
+		Statements in legacy character encodings are decoded by the encoding of their format, or by their byte order mark. Use --output-encoding to write the output in an encoding such as windows-1252, for programs that require it.
+
+		The converted statement is written to standard output, or with --output to a file. The file is written in full before it replaces any existing file, which is only done with --force. The output path may be a template with the fields {{.Dir}}, {{.Name}}, {{.Stem}} and {{.Ext}} of the input path, and {{.FromFormat}} and {{.ToFormat}}, as in '{{.Stem}}-{{.ToFormat}}.csv'.
 
-		Provide the path to the CSV file as an argument. The argument supports glob patterns, but the pattern must match exactly one file.
+		With several input files, an output path template gives one output file per input file. Otherwise, the transactions of all the files are sorted by date and written as one statement.
 
-		The file should be formatted according to the format specified by the required --from flag.`,
-		Args: cobra.ExactArgs(1),
+		Formats such as OFX identify the account of the statement. The account is taken from the statement when it tells, and otherwise from --account; the bank is given by --bank-id.
+
+		Rules enrich the transactions between reading and writing, for example by giving transactions with the description 'VIPPS*KIWI 123 OSLO' the payee 'Kiwi' and the category 'Groceries'. The rules are read from the YAML file given by --rules, or by the 'rules' key of the config file.
+
+		Rows that cannot be parsed stop the conversion by default. With --on-error=skip the rows are left out and listed when the conversion is done. With --on-error=collect the rows are left out too, but the command fails after listing them.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			opts.FilePath = args[0]
-			fmt.Printf("convert called on file: %s\n", opts.FilePath)
+			opts.FilePaths = args
+			if len(args) == 0 {
+				if opts.IO.IsStdinTTY() {
+					return errors.New("requires a path, or a statement piped to standard input")
+				}
+				opts.FilePaths = []string{stdinPath}
+			}
 
-			if opts.FromFormat == "" || opts.ToFormat == "" {
-				msg := "required flags '--from' and '--to' must not be empty"
+			if opts.ToFormat == "" {
+				msg := "required flag '--to' must not be empty"
 				return fmt.Errorf(msg)
 			}
 
+			switch opts.OnError {
+			case onErrorFail, onErrorSkip, onErrorCollect:
+			default:
+				return fmt.Errorf("invalid value '%s' for '--on-error': must be one of fail, skip or collect", opts.OnError)
+			}
+
+			if _, err := charset.Lookup(opts.OutputEncoding); err != nil {
+				return fmt.Errorf("invalid value '%s' for '--output-encoding': %v", opts.OutputEncoding, err)
+			}
+
+			if opts.Jobs < 1 {
+				return fmt.Errorf("invalid value %d for '--jobs': must be at least 1", opts.Jobs)
+			}
+
 			if runF != nil {
 				return runF(opts)
 			}
@@ -49,40 +128,549 @@ func NewCmdConvert(io *iostreams.IOStreams, runF func(*ConvertOptions) error) *c
 		},
 	}
 
-	cmd.Flags().StringVar(&opts.FromFormat, "from", "", "Name of input format (required)")
-	cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVar(&opts.FromFormat, "from", "", "Name of input format (detected if omitted)")
 	cmd.Flags().StringVar(&opts.ToFormat, "to", "", "Name of output format (required)")
 	cmd.MarkFlagRequired("to")
+	cmd.Flags().StringVar(&opts.OnError, "on-error", onErrorFail, "What to do with rows that cannot be parsed: fail, skip or collect")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write to a file, whose path may be a template, instead of standard output")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite the output file if it exists")
+	cmd.Flags().StringVar(&opts.OutputEncoding, "output-encoding", "", "Character encoding of the output, such as windows-1252 or UTF-16LE")
+	cmd.Flags().StringVar(&opts.Account, "account", "", "Account number written to formats that require one, such as OFX")
+	cmd.Flags().StringVar(&opts.BankId, "bank-id", "", "Bank identifier, such as a routing number or BIC, written to formats that require one, such as OFX")
+	cmd.Flags().StringVar(&opts.RulesPath, "rules", "", "YAML file with rules that set the payee, category, memo and tags of transactions")
+	cmd.Flags().IntVarP(&opts.Jobs, "jobs", "j", runtime.NumCPU(), "Number of files to convert at the same time")
 
 	return cmd
 }
 
 func convertRun(opts *ConvertOptions) error {
-	file, err := os.Open(opts.FilePath)
+	paths, err := expandPaths(opts.FilePaths)
 	if err != nil {
-		return fmt.Errorf("failed to open file %s: %v", opts.FilePath, err)
+		return err
 	}
-	defer file.Close()
-
-	regFactory := &csvstatement.Factory{InitRegistry: opts.Registry}
-	formatRegistry := csvstatement.NewRegistry(regFactory)
 
-	fromFormat, err := formatRegistry.Get(opts.FromFormat)
+	formatRegistry, err := newFormatRegistry(opts.Registry)
 	if err != nil {
-		return fmt.Errorf("failed to get format '%s': %v", opts.FromFormat, err)
+		return fmt.Errorf("failed to load formats: %v", err)
 	}
 
-	toFormat, err := formatRegistry.Get(opts.ToFormat)
+	codecs, err := newStatementRegistry(opts.Codecs)
 	if err != nil {
+		return fmt.Errorf("failed to load formats: %v", err)
+	}
+
+	// Check the output format before any file is read.
+	if _, err := getWriter(opts.ToFormat, opts.OutputEncoding, codecs, formatRegistry); err != nil {
 		msg := fmt.Sprintf("failed to get format '%s': %v", opts.ToFormat, err)
 		return fmt.Errorf(msg)
 	}
 
-	err = csvstatement.Convert(file, os.Stdout, fromFormat, toFormat)
+	c := &converter{
+		opts:    opts,
+		formats: formatRegistry,
+		codecs:  codecs,
+		outputs: map[string]string{},
+	}
+	if path := rulesPath(opts); path != "" {
+		if c.rules, err = rules.Load(path); err != nil {
+			return fmt.Errorf("failed to load rules: %v", err)
+		}
+	}
+	switch {
+	case len(paths) == 1:
+		err = c.convertFile(paths[0])
+	case strings.Contains(opts.Output, "{{"):
+		err = c.convertEach(paths)
+	default:
+		err = c.convertMerged(paths)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(c.skipped) > 0 {
+		fmt.Fprintf(opts.IO.Err, "Skipped %d rows that could not be parsed:\n", len(c.skipped))
+		for _, recErr := range c.skipped {
+			fmt.Fprintf(opts.IO.Err, "  %v\n", recErr)
+		}
+		if opts.OnError == onErrorCollect {
+			return fmt.Errorf("failed to convert bank statement: %d rows could not be parsed", len(c.skipped))
+		}
+	}
+
+	return nil
+}
+
+// converter converts statement files as set by ConvertOptions. Its methods
+// may be called from several goroutines.
+type converter struct {
+	opts    *ConvertOptions
+	formats *csvstatement.FormatRegistry
+	codecs  statement.Registry
+	rules   *rules.Rules // The rules that enrich the transactions, if any.
+
+	mu      sync.Mutex               // Guards the fields below, and writes to opts.IO.Err.
+	skipped []*statement.RecordError // The rows skipped in all files.
+	outputs map[string]string        // The input file of each output file.
+}
+
+// input is an open statement file, with the reader of its format.
+type input struct {
+	path    string
+	file    io.ReadCloser
+	source  *bufio.Reader
+	reader  statement.Reader
+	format  string
+	skipped []*statement.RecordError
+}
+
+// open opens the statement file at path, or standard input if path is
+// stdinPath, and detects its format unless it is given by the options.
+func (c *converter) open(path string) (*input, error) {
+	var file io.ReadCloser
+	if path == stdinPath {
+		file = io.NopCloser(c.opts.IO.In)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %v", path, err)
+		}
+		file = f
+	}
+
+	in := &input{
+		path:   path,
+		file:   file,
+		source: bufio.NewReaderSize(file, detectSampleSize),
+		format: c.opts.FromFormat,
+	}
+	var err error
+	if in.format == "" {
+		in.reader, in.format, err = detectReader(in.source, c.codecs, c.formats)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		c.printf("Detected input format of %s: %s\n", displayPath(path), in.format)
+	} else {
+		in.reader, err = getReader(in.format, c.codecs, c.formats)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to get format '%s': %v", in.format, err)
+		}
+	}
+
+	if named, ok := in.reader.(fileNamer); ok {
+		named.SetFileName(displayPath(path))
+	}
+	if logged, ok := in.reader.(logSetter); ok {
+		logged.SetLogger(slog.New(slog.NewTextHandler(lockedWriter{c}, nil)))
+	}
+	return in, nil
+}
+
+// transforms returns the transforms to apply to the transactions of in.
+func (c *converter) transforms(in *input) []statement.Transform {
+	var transforms []statement.Transform
+	if c.opts.OnError != onErrorFail {
+		transforms = append(transforms, statement.SkipRecordErrors(&in.skipped))
+	}
+	if c.rules != nil {
+		transforms = append(transforms, c.rules.Transform())
+	}
+	return transforms
+}
+
+// report prints the statement summaries of in, and records the rows that
+// were skipped, once in has been converted.
+func (c *converter) report(in *input) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if summarizer, ok := in.reader.(statement.Summarizer); ok {
+		printSummaries(c.opts.IO.Err, summarizer.Summaries())
+	}
+	if p, ok := in.reader.(preambler); ok {
+		printPreamble(c.opts.IO.Err, p.Preamble())
+	}
+	c.skipped = append(c.skipped, in.skipped...)
+}
+
+func (c *converter) printf(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.opts.IO.Err, format, args...)
+}
+
+// lockedWriter writes to the standard error of a converter, holding its
+// lock.
+type lockedWriter struct {
+	c *converter
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+	return w.c.opts.IO.Err.Write(p)
+}
+
+// convertFile converts the statement file at path to the output of the
+// options.
+func (c *converter) convertFile(path string) error {
+	in, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer in.file.Close()
+
+	writer, err := getWriter(c.opts.ToFormat, c.opts.OutputEncoding, c.codecs, c.formats)
+	if err != nil {
+		return fmt.Errorf("failed to get format '%s': %v", c.opts.ToFormat, err)
+	}
+	c.setUpWriter(writer, in.reader)
+	convert := func(target io.Writer) error {
+		return statement.Convert(in.source, target, in.reader, writer, c.transforms(in)...)
+	}
+
+	if c.opts.Output == "" || c.opts.Output == "-" {
+		err = convert(c.opts.IO.Out)
+	} else {
+		if path == stdinPath && strings.Contains(c.opts.Output, "{{") {
+			return errors.New("an output path template needs an input file, not standard input")
+		}
+		var outPath string
+		outPath, err = outputPath(c.opts.Output, path, in.format, c.opts.ToFormat)
+		if err != nil {
+			return err
+		}
+		if err := c.claimOutput(outPath, path); err != nil {
+			return err
+		}
+		err = writeFileAtomic(outPath, c.opts.Force, convert)
+		if err == nil {
+			c.printf("Wrote %s\n", outPath)
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("failed to convert bank statement: %v", err)
 		return fmt.Errorf(msg)
 	}
 
+	c.report(in)
+	return nil
+}
+
+// setUpWriter gives writer the account of the options, and the summaries of
+// reader, if writer takes them. reader is nil for merged statements.
+func (c *converter) setUpWriter(writer statement.Writer, reader statement.Reader) {
+	if encoded, ok := writer.(encodedWriter); ok {
+		writer = encoded.Writer
+	}
+	if w, ok := writer.(accountSetter); ok {
+		w.SetAccount(c.opts.BankId, c.opts.Account)
+	}
+	summarizer, ok := reader.(statement.Summarizer)
+	if w, takes := writer.(summariesSetter); ok && takes {
+		w.SetSummaries(summarizer.Summaries)
+	}
+}
+
+// claimOutput records that outPath is written for the input file at path, and
+// fails if it is already written for another input file.
+func (c *converter) claimOutput(outPath, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if other, ok := c.outputs[outPath]; ok {
+		return fmt.Errorf("output file %s is the same for %s and %s; use a template that gives different paths", outPath, other, path)
+	}
+	c.outputs[outPath] = path
+	return nil
+}
+
+// convertEach converts each of the files at paths to its own output file.
+func (c *converter) convertEach(paths []string) error {
+	errs := forEach(paths, c.opts.Jobs, func(_ int, path string) error {
+		return c.convertFile(path)
+	})
+	return c.checkErrors(paths, errs)
+}
+
+// convertMerged converts the files at paths to one statement, with the
+// transactions of all the files sorted by date.
+func (c *converter) convertMerged(paths []string) error {
+	results := make([][]domain.Transaction, len(paths))
+	errs := forEach(paths, c.opts.Jobs, func(i int, path string) error {
+		in, err := c.open(path)
+		if err != nil {
+			return err
+		}
+		defer in.file.Close()
+
+		txns := in.reader.All(in.source)
+		for _, transform := range c.transforms(in) {
+			txns = transform(txns)
+		}
+		for txn, err := range txns {
+			if err != nil {
+				return err
+			}
+			results[i] = append(results[i], txn)
+		}
+		c.report(in)
+		return nil
+	})
+	if err := c.checkErrors(paths, errs); err != nil {
+		return err
+	}
+
+	var all []domain.Transaction
+	for _, txns := range results {
+		all = append(all, txns...)
+	}
+	slices.SortStableFunc(all, func(a, b domain.Transaction) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	writer, err := getWriter(c.opts.ToFormat, c.opts.OutputEncoding, c.codecs, c.formats)
+	if err != nil {
+		return fmt.Errorf("failed to get format '%s': %v", c.opts.ToFormat, err)
+	}
+	c.setUpWriter(writer, nil)
+	write := func(target io.Writer) error {
+		return writer.WriteAll(target, func(yield func(domain.Transaction, error) bool) {
+			for _, txn := range all {
+				if !yield(txn, nil) {
+					return
+				}
+			}
+		})
+	}
+
+	if c.opts.Output == "" || c.opts.Output == "-" {
+		err = write(c.opts.IO.Out)
+	} else {
+		err = writeFileAtomic(c.opts.Output, c.opts.Force, write)
+		if err == nil {
+			c.printf("Wrote %s\n", c.opts.Output)
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("failed to convert bank statements: %v", err)
+		return fmt.Errorf(msg)
+	}
+	return nil
+}
+
+// checkErrors prints the error of each file that failed, and returns an
+// error if any did.
+func (c *converter) checkErrors(paths []string, errs []error) error {
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			c.printf("%s: %v\n", displayPath(paths[i]), err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to convert %d of %d files", failed, len(paths))
+	}
+	return nil
+}
+
+// forEach calls f for each of paths, with at most jobs calls running at the
+// same time, and returns the errors of the calls in the order of paths.
+func forEach(paths []string, jobs int, f func(i int, path string) error) []error {
+	errs := make([]error, len(paths))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(jobs, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = f(i, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
+
+// printSummaries writes the account and balances of each statement to w.
+func printSummaries(w io.Writer, summaries []statement.Summary) {
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "Statement %s\n", orNone(summary.Id, summary.Id == ""))
+		fmt.Fprintf(w, "  Account:         %s\n", orNone(summary.Account, summary.Account == ""))
+		fmt.Fprintf(w, "  Opening balance: %s\n", formatBalance(summary.Opening))
+		fmt.Fprintf(w, "  Closing balance: %s\n", formatBalance(summary.Closing))
+	}
+}
+
+// printPreamble writes the key/value pairs found in the preamble of a CSV
+// statement, sorted by key.
+func printPreamble(w io.Writer, preamble map[string]string) {
+	if len(preamble) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Statement details")
+	for _, key := range slices.Sorted(maps.Keys(preamble)) {
+		fmt.Fprintf(w, "  %s: %s\n", key, preamble[key])
+	}
+}
+
+func formatBalance(balance *statement.Balance) string {
+	if balance == nil {
+		return "(none)"
+	}
+	amount := money.Format(balance.Amount, money.Exponent(balance.Currency), '.')
+	if balance.Currency != "" {
+		amount += " " + balance.Currency
+	}
+	return fmt.Sprintf("%s on %s", amount, balance.Date.Format(time.DateOnly))
+}
+
+// preambler is implemented by readers that capture the preamble of a
+// statement.
+type preambler interface {
+	Preamble() map[string]string
+}
+
+// fileNamer is implemented by readers that report the name of the statement
+// file in errors.
+type fileNamer interface {
+	SetFileName(name string)
+}
+
+// rulesPath returns the path of the rules file given by the options or the
+// config. A relative path in the config file is relative to the directory of
+// the config file, rather than to the current directory.
+func rulesPath(opts *ConvertOptions) string {
+	if opts.RulesPath != "" {
+		return opts.RulesPath
+	}
+	path := viper.GetString("rules")
+	if path != "" && !filepath.IsAbs(path) && viper.InConfig("rules") {
+		path = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), path)
+	}
+	return path
+}
+
+// accountSetter is implemented by writers of formats that identify the
+// account of the statement.
+type accountSetter interface {
+	SetAccount(bankId, accountId string)
+}
+
+// summariesSetter is implemented by writers that write the balances of the
+// statement, see [statement.Summarizer].
+type summariesSetter interface {
+	SetSummaries(summaries func() []statement.Summary)
+}
+
+// logSetter is implemented by readers that log problems with the format of
+// the statement.
+type logSetter interface {
+	SetLogger(log *slog.Logger)
+}
+
+// getReader returns the reader of the format with the given id. Codecs take
+// precedence over CSV formats with the same id.
+func getReader(id string, codecs statement.Registry, formats *csvstatement.FormatRegistry) (statement.Reader, error) {
+	if codec, ok := codecs[id]; ok {
+		if codec.NewReader == nil {
+			return nil, fmt.Errorf("format '%s' can not be read", id)
+		}
+		return codec.NewReader(), nil
+	}
+	format, err := formats.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return csvstatement.NewParser(format), nil
+}
+
+// getWriter returns the writer of the format with the given id. Codecs take
+// precedence over CSV formats with the same id. A non-empty encoding replaces
+// the character encoding of the format.
+func getWriter(id, encoding string, codecs statement.Registry, formats *csvstatement.FormatRegistry) (statement.Writer, error) {
+	if codec, ok := codecs[id]; ok {
+		if codec.NewWriter == nil {
+			return nil, fmt.Errorf("format '%s' can not be written", id)
+		}
+		if encoding != "" && !charset.IsUTF8(encoding) {
+			return encodedWriter{Writer: codec.NewWriter(), encoding: encoding}, nil
+		}
+		return codec.NewWriter(), nil
+	}
+	format, err := formats.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if encoding != "" {
+		format.Encoding = encoding
+	}
+	return csvstatement.NewWriter(format), nil
+}
+
+// encodedWriter encodes the output of a statement writer, which writes UTF-8,
+// to another character encoding.
+type encodedWriter struct {
+	statement.Writer
+	encoding string
+}
+
+func (w encodedWriter) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	encoded, err := charset.NewWriter(target, w.encoding)
+	if err != nil {
+		return err
+	}
+	if err := w.Writer.WriteAll(encoded, txns); err != nil {
+		return err
+	}
+	if err := encoded.Close(); err != nil {
+		return fmt.Errorf("failed to encode output as %s: %v", w.encoding, err)
+	}
 	return nil
 }
+
+// detectReader detects the format of the statement in source, and returns its
+// reader and id. The codecs are tried before the CSV formats.
+func detectReader(source *bufio.Reader, codecs statement.Registry, formats *csvstatement.FormatRegistry) (statement.Reader, string, error) {
+	sample, err := peekSample(source)
+	if err != nil {
+		return nil, "", err
+	}
+	if codec, ok := codecs.Detect(sample); ok {
+		return codec.NewReader(), codec.Id, nil
+	}
+	format, err := formats.Detect(sample)
+	if err != nil {
+		return nil, "", err
+	}
+	return csvstatement.NewParser(format), format.Id, nil
+}
+
+// detectSampleSize is the number of bytes read from the start of a statement
+// to detect its format.
+const detectSampleSize = 16 * 1024
+
+// peekSample returns the first lines of the statement in source, without
+// consuming them from source.
+func peekSample(source *bufio.Reader) ([]byte, error) {
+	sample, err := source.Peek(detectSampleSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read statement: %v", err)
+	}
+	if len(sample) == detectSampleSize {
+		// Leave out the last line, as it is most likely cut off.
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	}
+	return sample, nil
+}
@@ -3,15 +3,20 @@ package cmd
 import (
 	"bytes"
 	"fincli/internal/iostreams"
+	"fincli/internal/ofx"
+	"fincli/internal/statement"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // TODO: What should happen if run in non-interactive mode?
-// TODO: What should happen if stdin is not TTY?
 // TODO: What should happen if stdout is not TTY?
 // Are there situations when stdin/out is TTY, but we still can't prompt the user?
 
@@ -28,11 +33,122 @@ func TestNewCmdConvert(t *testing.T) {
 			cli:      "path/to/file --from FROM_FORMAT --to TO_FORMAT",
 			wantsErr: false,
 			wantsOpts: ConvertOptions{
-				FilePath:   "path/to/file",
+				FilePaths:  []string{"path/to/file"},
 				FromFormat: "FROM_FORMAT",
 				ToFormat:   "TO_FORMAT",
+				OnError:    "fail",
 			},
 		},
+		{
+			name:     "detect input format",
+			cli:      "path/to/file --to TO_FORMAT",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths: []string{"path/to/file"},
+				ToFormat:  "TO_FORMAT",
+				OnError:   "fail",
+			},
+		},
+		{
+			name:     "skip bad rows",
+			cli:      "path/to/file --to TO_FORMAT --on-error skip",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths: []string{"path/to/file"},
+				ToFormat:  "TO_FORMAT",
+				OnError:   "skip",
+			},
+		},
+		{
+			name:     "write to file",
+			cli:      "path/to/file --to TO_FORMAT -o {{.Stem}}.out --force",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths: []string{"path/to/file"},
+				ToFormat:  "TO_FORMAT",
+				OnError:   "fail",
+				Output:    "{{.Stem}}.out",
+				Force:     true,
+			},
+		},
+		{
+			name:     "convert several files",
+			cli:      "a.csv b.csv --to TO_FORMAT -j 2",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths: []string{"a.csv", "b.csv"},
+				ToFormat:  "TO_FORMAT",
+				OnError:   "fail",
+				Jobs:      2,
+			},
+		},
+		{
+			name:     "read from standard input",
+			cli:      "- --from FROM_FORMAT --to TO_FORMAT",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths:  []string{"-"},
+				FromFormat: "FROM_FORMAT",
+				ToFormat:   "TO_FORMAT",
+				OnError:    "fail",
+			},
+		},
+		{
+			name:     "read from piped standard input without path",
+			cli:      "--from FROM_FORMAT --to TO_FORMAT",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths:  []string{"-"},
+				FromFormat: "FROM_FORMAT",
+				ToFormat:   "TO_FORMAT",
+				OnError:    "fail",
+			},
+		},
+		{
+			name:     "output encoding",
+			cli:      "path/to/file --to TO_FORMAT --output-encoding windows-1252",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths:      []string{"path/to/file"},
+				ToFormat:       "TO_FORMAT",
+				OnError:        "fail",
+				OutputEncoding: "windows-1252",
+			},
+		},
+		{
+			name:        "unknown output encoding",
+			cli:         "path/to/file --to TO_FORMAT --output-encoding klingon",
+			wantsErr:    true,
+			wantsErrMsg: "invalid value 'klingon' for '--output-encoding': unknown character encoding 'klingon'",
+		},
+		{
+			name:     "rules",
+			cli:      "path/to/file --to TO_FORMAT --rules rules.yaml",
+			wantsErr: false,
+			wantsOpts: ConvertOptions{
+				FilePaths: []string{"path/to/file"},
+				ToFormat:  "TO_FORMAT",
+				OnError:   "fail",
+				RulesPath: "rules.yaml",
+			},
+		},
+		{
+			name:        "invalid number of jobs",
+			cli:         "path/to/file --to TO_FORMAT --jobs 0",
+			wantsErr:    true,
+			wantsErrMsg: "invalid value 0 for '--jobs': must be at least 1",
+		},
+		{
+			name:        "invalid error mode",
+			cli:         "path/to/file --to TO_FORMAT --on-error ignore",
+			wantsErr:    true,
+			wantsErrMsg: "invalid value 'ignore' for '--on-error': must be one of fail, skip or collect",
+		},
+		{
+			name:     "missing output format",
+			cli:      "path/to/file --from FROM_FORMAT",
+			wantsErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -72,21 +188,394 @@ func TestNewCmdConvert(t *testing.T) {
 			}
 			require.NoError(t, err)
 
-			assert.Equal(t, tt.wantsOpts.FilePath, opts.FilePath)
+			assert.Equal(t, tt.wantsOpts.FilePaths, opts.FilePaths)
 			assert.Equal(t, tt.wantsOpts.FromFormat, opts.FromFormat)
 			assert.Equal(t, tt.wantsOpts.ToFormat, opts.ToFormat)
+			assert.Equal(t, tt.wantsOpts.OnError, opts.OnError)
+			assert.Equal(t, tt.wantsOpts.Output, opts.Output)
+			assert.Equal(t, tt.wantsOpts.Force, opts.Force)
+			assert.Equal(t, tt.wantsOpts.OutputEncoding, opts.OutputEncoding)
+			assert.Equal(t, tt.wantsOpts.RulesPath, opts.RulesPath)
+			if tt.wantsOpts.Jobs != 0 {
+				assert.Equal(t, tt.wantsOpts.Jobs, opts.Jobs)
+			}
 		})
 	}
 }
 
 func Test_convertRun(t *testing.T) {
+	input := "Date,Amount\n2025-01-01,12.50\n"
+	want := "Date,Amount\n2025-01-01,12.50\n"
+
 	tests := []struct {
-		name    string
-		wantErr bool
-	}{} // no tests yet
+		name      string
+		output    string // Relative to the test directory.
+		existing  string // Content of the output file before converting.
+		force     bool
+		wantsErr  string
+		wantsOut  string
+		wantsFile string
+	}{
+		{
+			name:     "standard output",
+			wantsOut: want,
+		},
+		{
+			name:      "output file",
+			output:    "out/converted.csv",
+			wantsFile: want,
+		},
+		{
+			name:      "output path template",
+			output:    "{{.Stem}}-{{.ToFormat}}.csv",
+			wantsFile: want,
+		},
+		{
+			name:      "refuse to overwrite",
+			output:    "converted.csv",
+			existing:  "old",
+			wantsErr:  "already exists, use --force to overwrite",
+			wantsFile: "old",
+		},
+		{
+			name:      "overwrite with force",
+			output:    "converted.csv",
+			existing:  "old",
+			force:     true,
+			wantsFile: want,
+		},
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.True(t, false)
+			dir := t.TempDir()
+			inputPath := filepath.Join(dir, "january.csv")
+			require.NoError(t, os.WriteFile(inputPath, []byte(input), 0o644))
+
+			var outPath string
+			if tt.output != "" {
+				outPath = filepath.Join(dir, "january-both.csv")
+				if !strings.Contains(tt.output, "{{") {
+					outPath = filepath.Join(dir, tt.output)
+				}
+			}
+			if tt.existing != "" {
+				require.NoError(t, os.WriteFile(outPath, []byte(tt.existing), 0o644))
+			}
+
+			out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+			opts := &ConvertOptions{
+				IO:         &iostreams.IOStreams{Out: out, Err: errOut},
+				Registry:   testRegistry(),
+				Codecs:     testCodecs(),
+				FilePaths:  []string{inputPath},
+				FromFormat: "both",
+				ToFormat:   "both",
+				OnError:    onErrorFail,
+				Force:      tt.force,
+				Jobs:       1,
+			}
+			if tt.output != "" {
+				opts.Output = filepath.Join(dir, tt.output)
+			}
+
+			err := convertRun(opts)
+			if tt.wantsErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantsErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantsOut, out.String())
+
+			if outPath != "" {
+				data, err := os.ReadFile(outPath)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantsFile, string(data))
+
+				entries, err := os.ReadDir(filepath.Dir(outPath))
+				require.NoError(t, err)
+				for _, entry := range entries {
+					assert.NotContains(t, entry.Name(), ".tmp-", "temporary file left behind")
+				}
+			}
 		})
 	}
 }
+
+func Test_convertRun_severalFiles(t *testing.T) {
+	dir := t.TempDir()
+	inputs := map[string]string{
+		"february.csv": "Date,Amount\n2025-02-01,3.00\n2025-01-15,2.00\n",
+		"january.csv":  "Date,Amount\n2025-01-01,1.00\n",
+		".hidden.csv":  "Date,Amount\n2025-03-01,4.00\n",
+	}
+	for name, data := range inputs {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644))
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		output    string
+		wantsOut  string
+		wantsErr  string
+		wantFiles map[string]string
+	}{
+		{
+			name:     "merged and sorted by date",
+			args:     []string{dir},
+			wantsOut: "Date,Amount\n2025-01-01,1.00\n2025-01-15,2.00\n2025-02-01,3.00\n",
+		},
+		{
+			name:   "one output file per input file",
+			args:   []string{filepath.Join(dir, "*.csv")},
+			output: filepath.Join(dir, "out", "{{.Stem}}.csv"),
+			wantFiles: map[string]string{
+				"february.csv": inputs["february.csv"],
+				"january.csv":  inputs["january.csv"],
+			},
+		},
+		{
+			name:     "same output path for two files",
+			args:     []string{dir},
+			output:   filepath.Join(dir, "out", "{{.Ext}}"),
+			wantsErr: "failed to convert 1 of 2 files",
+		},
+		{
+			name:     "glob without matches",
+			args:     []string{filepath.Join(dir, "*.ofx")},
+			wantsErr: "no files match",
+		},
+		{
+			name:     "missing file",
+			args:     []string{filepath.Join(dir, "january.csv"), filepath.Join(dir, "march.csv")},
+			wantsErr: "failed to convert 1 of 2 files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.RemoveAll(filepath.Join(dir, "out"))
+
+			out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+			opts := &ConvertOptions{
+				IO:         &iostreams.IOStreams{Out: out, Err: errOut},
+				Registry:   testRegistry(),
+				Codecs:     testCodecs(),
+				FilePaths:  tt.args,
+				FromFormat: "both",
+				ToFormat:   "both",
+				OnError:    onErrorFail,
+				Output:     tt.output,
+				Jobs:       2,
+			}
+
+			err := convertRun(opts)
+			if tt.wantsErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantsErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantsOut, out.String())
+
+			for name, want := range tt.wantFiles {
+				data, err := os.ReadFile(filepath.Join(dir, "out", name))
+				require.NoError(t, err)
+				assert.Equal(t, want, string(data))
+			}
+		})
+	}
+}
+
+func Test_convertRun_stdin(t *testing.T) {
+	tests := []struct {
+		name       string
+		fromFormat string
+		output     string
+		wantsOut   string
+		wantsErr   string
+	}{
+		{
+			name:       "given format",
+			fromFormat: "both",
+			wantsOut:   "Date,Amount\n2025-01-01,12.50\n",
+		},
+		{
+			name:     "detected format",
+			wantsOut: "Date,Amount\n2025-01-01,12.50\n",
+		},
+		{
+			name:       "output path template",
+			fromFormat: "both",
+			output:     "{{.Stem}}.csv",
+			wantsErr:   "an output path template needs an input file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+			opts := &ConvertOptions{
+				IO: &iostreams.IOStreams{
+					In:  strings.NewReader("Date,Amount\n2025-01-01,12.50\n"),
+					Out: out,
+					Err: errOut,
+				},
+				Registry:   testRegistry(),
+				Codecs:     testCodecs(),
+				FilePaths:  []string{"-"},
+				FromFormat: tt.fromFormat,
+				ToFormat:   "both",
+				OnError:    onErrorFail,
+				Output:     tt.output,
+				Jobs:       1,
+			}
+
+			err := convertRun(opts)
+			if tt.wantsErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantsErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantsOut, out.String())
+		})
+	}
+}
+
+func Test_convertRun_rules(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "january.csv")
+	require.NoError(t, os.WriteFile(inputPath, []byte("Date,Amount\n2025-01-01,12.50\n2025-01-02,1.00\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		rules    string
+		wantsErr string
+	}{
+		{
+			name:  "apply rules",
+			rules: "rules:\n  - match:\n      min_amount: \"10\"\n    set:\n      payee: Kiwi\n",
+		},
+		{
+			name:     "invalid rules",
+			rules:    "rules:\n  - match:\n      payee: '('\n",
+			wantsErr: "failed to load rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesPath := filepath.Join(dir, "rules.yaml")
+			require.NoError(t, os.WriteFile(rulesPath, []byte(tt.rules), 0o644))
+
+			out := new(bytes.Buffer)
+			opts := &ConvertOptions{
+				IO:         &iostreams.IOStreams{Out: out, Err: new(bytes.Buffer)},
+				Registry:   testRegistry(),
+				Codecs:     testCodecs(),
+				FilePaths:  []string{inputPath},
+				FromFormat: "both",
+				ToFormat:   "ofx",
+				OnError:    onErrorFail,
+				Account:    "12345678903",
+				BankId:     "8601",
+				RulesPath:  rulesPath,
+				Jobs:       1,
+			}
+
+			err := convertRun(opts)
+			if tt.wantsErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantsErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, strings.Count(out.String(), "<NAME>Kiwi</NAME>"))
+		})
+	}
+}
+
+func Test_convertRun_log(t *testing.T) {
+	dir := t.TempDir()
+	goodPath := filepath.Join(dir, "good.csv")
+	require.NoError(t, os.WriteFile(goodPath, []byte("Date,Amount\n2025-01-01,12.50\n"), 0o644))
+	shortPath := filepath.Join(dir, "short.csv")
+	require.NoError(t, os.WriteFile(shortPath, []byte("Dato;Beløp\n2025-01-01;1,00\n"), 0o644))
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	opts := &ConvertOptions{
+		IO:         &iostreams.IOStreams{Out: out, Err: errOut},
+		Registry:   testRegistry(),
+		Codecs:     testCodecs(),
+		FilePaths:  []string{goodPath},
+		FromFormat: "both",
+		ToFormat:   "both",
+		OnError:    onErrorFail,
+		Jobs:       1,
+	}
+	require.NoError(t, convertRun(opts))
+	assert.Empty(t, errOut.String())
+
+	// The readonly format maps a column to a position the file does not have.
+	opts.FilePaths = []string{shortPath}
+	opts.FromFormat = "readonly"
+	require.NoError(t, convertRun(opts))
+	assert.Contains(t, errOut.String(), "is mapped to position 3")
+
+	// Files converted at the same time log to the same stream.
+	errOut.Reset()
+	opts.FilePaths = nil
+	for i := range 4 {
+		path := filepath.Join(dir, fmt.Sprintf("short%d.csv", i))
+		require.NoError(t, os.WriteFile(path, []byte("Dato;Beløp\n2025-01-01;1,00\n"), 0o644))
+		opts.FilePaths = append(opts.FilePaths, path)
+	}
+	opts.Jobs = 4
+	require.NoError(t, convertRun(opts))
+	assert.Equal(t, 4, strings.Count(errOut.String(), "is mapped to position 3"))
+}
+
+func Test_rulesPath(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".fincli.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("rules: rules.yaml\n"), 0o644))
+
+	viper.SetConfigFile(configPath)
+	require.NoError(t, viper.ReadInConfig())
+	t.Cleanup(viper.Reset)
+
+	assert.Equal(t, filepath.Join(dir, "rules.yaml"), rulesPath(&ConvertOptions{}))
+	assert.Equal(t, "mine.yaml", rulesPath(&ConvertOptions{RulesPath: "mine.yaml"}))
+
+	viper.Set("rules", "/etc/fincli/rules.yaml")
+	assert.Equal(t, "/etc/fincli/rules.yaml", rulesPath(&ConvertOptions{}))
+}
+
+// summarizingReader is a reader with a fixed statement summary.
+type summarizingReader struct {
+	statement.Reader
+	summaries []statement.Summary
+}
+
+func (r summarizingReader) Summaries() []statement.Summary {
+	return r.summaries
+}
+
+func Test_converter_setUpWriter(t *testing.T) {
+	c := &converter{opts: &ConvertOptions{Account: "5678", BankId: "1234"}}
+	reader := summarizingReader{summaries: []statement.Summary{{Account: "9999"}}}
+
+	writer := ofx.NewWriter()
+	c.setUpWriter(encodedWriter{Writer: writer, encoding: "windows-1252"}, reader)
+	assert.Equal(t, "1234", writer.BankId)
+	assert.Equal(t, "5678", writer.AccountId)
+	require.NotNil(t, writer.Summaries)
+	assert.Equal(t, reader.summaries, writer.Summaries())
+
+	writer = ofx.NewWriter()
+	c.setUpWriter(writer, nil)
+	assert.Nil(t, writer.Summaries)
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"cmp"
+	"fincli/internal/csvstatement"
+	"fincli/internal/iostreams"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCmdFormats(io *iostreams.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "formats",
+		Short: "List, inspect and validate statement formats",
+		Long: `Work with the statement formats accepted by the --from and --to flags.
+
+		Formats are read from the built-in layouts and from YAML layout files in the
+		user layout directory (default is $HOME/.config/fincli/layouts).`,
+	}
+
+	cmd.AddCommand(NewCmdFormatsList(io, nil))
+	cmd.AddCommand(NewCmdFormatsShow(io, nil))
+	cmd.AddCommand(NewCmdFormatsNew(io, nil))
+	cmd.AddCommand(NewCmdFormatsValidate(io, nil))
+
+	return cmd
+}
+
+type FormatsListOptions struct {
+	IO       *iostreams.IOStreams
+	Registry *csvstatement.FormatRegistry
+	Codecs   statement.Registry
+}
+
+func NewCmdFormatsList(io *iostreams.IOStreams, runF func(*FormatsListOptions) error) *cobra.Command {
+	opts := &FormatsListOptions{
+		IO: io,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the known formats",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runF != nil {
+				return runF(opts)
+			}
+			return formatsListRun(opts)
+		},
+	}
+
+	return cmd
+}
+
+func formatsListRun(opts *FormatsListOptions) error {
+	registry, err := newFormatRegistry(opts.Registry)
+	if err != nil {
+		return fmt.Errorf("failed to load formats: %v", err)
+	}
+
+	codecs, err := newStatementRegistry(opts.Codecs)
+	if err != nil {
+		return fmt.Errorf("failed to load formats: %v", err)
+	}
+
+	ids := make([]string, 0, len(*registry)+len(codecs))
+	for id := range *registry {
+		ids = append(ids, id)
+	}
+	for id := range codecs {
+		if _, ok := (*registry)[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	w := tabwriter.NewWriter(opts.IO.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDIRECTION\tSOURCE")
+	for _, id := range ids {
+		if codec, ok := codecs[id]; ok {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", id, codecDirection(codec), "builtin")
+			continue
+		}
+		format := (*registry)[id]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", id, direction(format), format.Source)
+	}
+	return w.Flush()
+}
+
+// direction describes whether the format can be used with --from, --to or
+// both.
+func direction(format csvstatement.Format) string {
+	var dirs []string
+	if format.CanRead() {
+		dirs = append(dirs, "read")
+	}
+	if format.CanWrite() {
+		dirs = append(dirs, "write")
+	}
+	if len(dirs) == 0 {
+		return "-"
+	}
+	return strings.Join(dirs, "/")
+}
+
+// codecDirection is like direction, but for a statement codec.
+func codecDirection(codec statement.Codec) string {
+	switch {
+	case codec.NewReader != nil && codec.NewWriter != nil:
+		return "read/write"
+	case codec.NewReader != nil:
+		return "read"
+	case codec.NewWriter != nil:
+		return "write"
+	}
+	return "-"
+}
+
+type FormatsShowOptions struct {
+	IO       *iostreams.IOStreams
+	Registry *csvstatement.FormatRegistry
+	Codecs   statement.Registry
+
+	Id string
+}
+
+func NewCmdFormatsShow(io *iostreams.IOStreams, runF func(*FormatsShowOptions) error) *cobra.Command {
+	opts := &FormatsShowOptions{
+		IO: io,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show the details of a format",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Id = args[0]
+			if runF != nil {
+				return runF(opts)
+			}
+			return formatsShowRun(opts)
+		},
+	}
+
+	return cmd
+}
+
+func formatsShowRun(opts *FormatsShowOptions) error {
+	registry, err := newFormatRegistry(opts.Registry)
+	if err != nil {
+		return fmt.Errorf("failed to load formats: %v", err)
+	}
+
+	codecs, err := newStatementRegistry(opts.Codecs)
+	if err != nil {
+		return fmt.Errorf("failed to load formats: %v", err)
+	}
+	if codec, ok := codecs[opts.Id]; ok {
+		return printCodec(opts.IO.Out, codec)
+	}
+
+	format, err := registry.Get(opts.Id)
+	if err != nil {
+		return err
+	}
+	return printFormat(opts.IO.Out, format)
+}
+
+// printCodec writes a human readable description of codec to out.
+func printCodec(out io.Writer, codec statement.Codec) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Id:\t%s\n", codec.Id)
+	fmt.Fprintf(w, "Source:\t%s\n", "builtin")
+	fmt.Fprintf(w, "Direction:\t%s\n", codecDirection(codec))
+	fmt.Fprintf(w, "Description:\t%s\n", codec.Description)
+	return w.Flush()
+}
+
+// printFormat writes a human readable description of format to out.
+func printFormat(out io.Writer, format csvstatement.Format) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Id:\t%s\n", format.Id)
+	fmt.Fprintf(w, "Source:\t%s\n", format.Source)
+	fmt.Fprintf(w, "Direction:\t%s\n", direction(format))
+	fmt.Fprintf(w, "Delimiter:\t%s\n", quoteRune(format.Delimiter))
+	fmt.Fprintf(w, "Header:\t%t\n", format.HasHeader)
+	fmt.Fprintf(w, "Match headers:\t%t\n", format.MatchHeaders)
+	fmt.Fprintf(w, "Date format:\t%s\n", format.DateFormat)
+	fmt.Fprintf(w, "Other date formats:\t%s\n", orNone(strings.Join(format.DateFormats, ", "), len(format.DateFormats) == 0))
+	fmt.Fprintf(w, "Time zone:\t%s\n", cmp.Or(format.TimeZone, "UTC"))
+	fmt.Fprintf(w, "Decimal separator:\t%s\n", quoteRune(format.DecimalSeparator))
+	fmt.Fprintf(w, "Thousands separator:\t%s\n", orNone(quoteRune(format.ThousandsSeparator), format.ThousandsSeparator == 0))
+	fmt.Fprintf(w, "Currency:\t%s\n", orNone(format.Currency, format.Currency == ""))
+	fmt.Fprintf(w, "Encoding:\t%s\n", cmp.Or(format.Encoding, "UTF-8"))
+	fmt.Fprintf(w, "Skip lines:\t%d\n", format.SkipLines)
+	fmt.Fprintf(w, "Header row pattern:\t%s\n", orNone(format.HeaderRowPattern, format.HeaderRowPattern == ""))
+	fmt.Fprintf(w, "Footer pattern:\t%s\n", orNone(format.FooterPattern, format.FooterPattern == ""))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Columns:")
+	cols := slices.Clone(format.ColumnMappings)
+	slices.SortStableFunc(cols, func(a, b csvstatement.TransactionColumn) int {
+		return a.Pos - b.Pos
+	})
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  POS\tNAME\tKIND")
+	for _, col := range cols {
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", col.Pos, col.Name, col.Kind)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(format.Fields) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Composed fields:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  KIND\tTEMPLATE")
+	for _, field := range format.Fields {
+		fmt.Fprintf(w, "  %s\t%s\n", field.Kind, field.Template)
+	}
+	return w.Flush()
+}
+
+// orNone returns value, or "(none)" if unset.
+func orNone(value string, unset bool) string {
+	if unset {
+		return "(none)"
+	}
+	return value
+}
+
+func quoteRune(r rune) string {
+	if r == 0 {
+		return "(default)"
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+type FormatsValidateOptions struct {
+	IO *iostreams.IOStreams
+
+	FilePath string
+}
+
+func NewCmdFormatsValidate(io *iostreams.IOStreams, runF func(*FormatsValidateOptions) error) *cobra.Command {
+	opts := &FormatsValidateOptions{
+		IO: io,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Check a YAML layout file for problems",
+		Long: `Check a YAML layout file for problems.
+
+		Reports duplicate column positions, gaps between positions, and missing date
+		or amount columns, and tells whether the layout can be used to read, write
+		or both.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.FilePath = args[0]
+			if runF != nil {
+				return runF(opts)
+			}
+			return formatsValidateRun(opts)
+		},
+	}
+
+	return cmd
+}
+
+func formatsValidateRun(opts *FormatsValidateOptions) error {
+	format, err := csvstatement.ReadLayoutFile(opts.FilePath)
+	if err != nil {
+		return err
+	}
+
+	problems := format.Validate()
+	for _, problem := range problems {
+		var blocks []string
+		if problem.Read {
+			blocks = append(blocks, "read")
+		}
+		if problem.Write {
+			blocks = append(blocks, "write")
+		}
+		fmt.Fprintf(opts.IO.Out, "%s: %s (prevents %s)\n", opts.FilePath, problem, strings.Join(blocks, "/"))
+	}
+
+	if !format.CanRead() && !format.CanWrite() {
+		return fmt.Errorf("layout '%s' can be used neither for reading nor writing", format.Id)
+	}
+	fmt.Fprintf(opts.IO.Out, "%s: layout '%s' supports %s\n", opts.FilePath, format.Id, direction(format))
+	return nil
+}
@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
+	"fincli/internal/iostreams"
+	"fincli/internal/money"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+)
+
+type FormatsNewOptions struct {
+	IO *iostreams.IOStreams
+
+	SamplePath string
+	OutputPath string
+	Force      bool
+}
+
+func NewCmdFormatsNew(io *iostreams.IOStreams, runF func(*FormatsNewOptions) error) *cobra.Command {
+	opts := &FormatsNewOptions{
+		IO: io,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "new <sample.csv>",
+		Short: "Create a layout interactively from a sample statement",
+		Long: `Create a new YAML layout from a sample CSV statement.
+
+		The delimiter, header, date format and decimal separator are guessed from the
+		sample, and you are asked to confirm them and to map each column to a field.
+		A preview of the parsed transactions is shown while mapping the columns.
+
+		The layout is saved in the user layout directory, unless --output is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.SamplePath = args[0]
+			if runF != nil {
+				return runF(opts)
+			}
+			return formatsNewRun(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.OutputPath, "output", "o", "", "Path of the layout file to write")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite an existing layout file")
+
+	return cmd
+}
+
+// wizardSampleSize is the number of bytes read from the sample statement.
+const wizardSampleSize = 16 * 1024
+
+// wizardPreviewRows is the number of transactions shown in the preview.
+const wizardPreviewRows = 5
+
+// fieldSkip is used in the wizard for columns that are not mapped.
+const fieldSkip csvstatement.FieldKind = ""
+
+func formatsNewRun(opts *FormatsNewOptions) error {
+	file, err := os.Open(opts.SamplePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %v", opts.SamplePath, err)
+	}
+	defer file.Close()
+
+	sample, err := bufio.NewReaderSize(file, wizardSampleSize).Peek(wizardSampleSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return fmt.Errorf("failed to read sample: %v", err)
+	}
+	if len(sample) == wizardSampleSize {
+		// Leave out the last line, as it is most likely cut off.
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	}
+
+	format, err := csvstatement.GuessFormat(sample)
+	if err != nil {
+		return err
+	}
+	format.Id = strings.TrimSuffix(filepath.Base(opts.SamplePath), filepath.Ext(opts.SamplePath))
+
+	guessedDelimiter := format.Delimiter
+	if err := runSettingsForm(opts.IO, &format); err != nil {
+		return err
+	}
+	if format.Delimiter != guessedDelimiter {
+		// The guessed columns were split with the other delimiter.
+		format.ColumnMappings = nil
+	}
+	// Match columns by name, so the layout keeps working if the bank adds
+	// columns to its exports.
+	format.MatchHeaders = format.HasHeader
+
+	records, err := readWizardSample(sample, format.Delimiter)
+	if err != nil {
+		return err
+	}
+	if err := runColumnsForm(opts.IO, &format, sample, records); err != nil {
+		return err
+	}
+
+	for _, problem := range format.Validate() {
+		fmt.Fprintf(opts.IO.Err, "Warning: %s\n", problem)
+	}
+
+	path := opts.OutputPath
+	if path == "" {
+		dirs := layoutDirs()
+		if len(dirs) == 0 {
+			return fmt.Errorf("could not find the user layout directory, use --output")
+		}
+		path = filepath.Join(dirs[0], format.Id+".yaml")
+	}
+	if err := saveLayout(path, format, opts.Force); err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.IO.Err, "Saved layout '%s' to %s\n", format.Id, path)
+	return nil
+}
+
+// runSettingsForm asks the user to confirm the guessed file settings.
+func runSettingsForm(io *iostreams.IOStreams, format *csvstatement.Format) error {
+	dateFormats := huh.NewOptions(csvstatement.DateLayouts...)
+	delimiters := make([]huh.Option[rune], 0, len(csvstatement.Delimiters))
+	for _, d := range csvstatement.Delimiters {
+		delimiters = append(delimiters, huh.NewOption(fmt.Sprintf("%q", d), d))
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Layout id").
+				Description("The name to use with --from and --to").
+				Value(&format.Id).
+				Validate(validateLayoutId),
+			huh.NewSelect[rune]().
+				Title("Delimiter").
+				Options(delimiters...).
+				Value(&format.Delimiter),
+			huh.NewConfirm().
+				Title("Does the first line hold column names?").
+				Value(&format.HasHeader),
+			huh.NewSelect[string]().
+				Title("Date format").
+				Options(dateFormats...).
+				Value(&format.DateFormat),
+			huh.NewSelect[rune]().
+				Title("Decimal separator").
+				Options(huh.NewOption("','", ','), huh.NewOption("'.'", '.')).
+				Value(&format.DecimalSeparator),
+		),
+	)
+	return form.WithInput(io.In).WithOutput(io.Err).Run()
+}
+
+// runColumnsForm asks the user to map each column in records to a field, and
+// stores the mapping in format.
+func runColumnsForm(io *iostreams.IOStreams, format *csvstatement.Format, sample []byte, records [][]string) error {
+	header := records[0]
+	data := records
+	if format.HasHeader {
+		data = records[1:]
+	}
+
+	kindOptions := []huh.Option[csvstatement.FieldKind]{
+		huh.NewOption("skip", fieldSkip),
+		huh.NewOption("date", csvstatement.FieldDate),
+		huh.NewOption("payee", csvstatement.FieldPayee),
+		huh.NewOption("memo", csvstatement.FieldMemo),
+		huh.NewOption("inflow", csvstatement.FieldInflow),
+		huh.NewOption("outflow", csvstatement.FieldOutflow),
+		huh.NewOption("amount", csvstatement.FieldAmount),
+		huh.NewOption("direction", csvstatement.FieldDirection),
+		huh.NewOption("currency", csvstatement.FieldCurrency),
+		huh.NewOption("booking date", csvstatement.FieldBookingDate),
+		huh.NewOption("value date", csvstatement.FieldValueDate),
+		huh.NewOption("category", csvstatement.FieldCategory),
+		huh.NewOption("subcategory", csvstatement.FieldSubcategory),
+		huh.NewOption("tags", csvstatement.FieldTags),
+		huh.NewOption("notes", csvstatement.FieldNotes),
+	}
+
+	mapping := &columnMapping{Kinds: mappedKinds(format.ColumnMappings, len(header))}
+
+	names := make([]string, len(header))
+	var fields []huh.Field
+	for i := range header {
+		names[i] = fmt.Sprintf("Column %d", i+1)
+		if format.HasHeader {
+			names[i] = header[i]
+		}
+		fields = append(fields, huh.NewSelect[csvstatement.FieldKind]().
+			Title(names[i]).
+			Description(sampleValues(data, i)).
+			Options(kindOptions...).
+			Inline(true).
+			Value(&mapping.Kinds[i]))
+	}
+
+	columns := func() []csvstatement.TransactionColumn {
+		var cols []csvstatement.TransactionColumn
+		for i, kind := range mapping.Kinds {
+			if kind != fieldSkip {
+				cols = append(cols, csvstatement.TransactionColumn{Name: names[i], Kind: kind, Pos: i + 1})
+			}
+		}
+		return cols
+	}
+
+	fields = append(fields, huh.NewNote().
+		Title("Preview").
+		DescriptionFunc(func() string {
+			preview := *format
+			preview.ColumnMappings = columns()
+			return previewTransactions(preview, sample)
+		}, mapping))
+
+	form := huh.NewForm(huh.NewGroup(fields...))
+	if err := form.WithInput(io.In).WithOutput(io.Err).Run(); err != nil {
+		return err
+	}
+	format.ColumnMappings = columns()
+	return nil
+}
+
+// mappedKinds returns the field kind of each of n columns in cols. Columns
+// with a position outside the n columns are left out.
+func mappedKinds(cols []csvstatement.TransactionColumn, n int) []csvstatement.FieldKind {
+	kinds := make([]csvstatement.FieldKind, n)
+	for _, col := range cols {
+		if col.Pos >= 1 && col.Pos <= n {
+			kinds[col.Pos-1] = col.Kind
+		}
+	}
+	return kinds
+}
+
+// columnMapping holds the field kind chosen for each column in the wizard.
+type columnMapping struct {
+	Kinds []csvstatement.FieldKind
+}
+
+func validateLayoutId(id string) error {
+	if id == "" {
+		return errors.New("id must not be empty")
+	}
+	if strings.ContainsAny(id, `/\ `) {
+		return errors.New("id must not contain slashes or spaces")
+	}
+	return nil
+}
+
+func readWizardSample(sample []byte, delimiter rune) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("sample is empty")
+	}
+	return records, nil
+}
+
+// sampleValues returns the first few distinct non-empty values in column col.
+func sampleValues(records [][]string, col int) string {
+	var values []string
+	seen := map[string]bool{}
+	for _, rec := range records {
+		if col >= len(rec) || rec[col] == "" || seen[rec[col]] {
+			continue
+		}
+		seen[rec[col]] = true
+		values = append(values, rec[col])
+		if len(values) == 3 {
+			break
+		}
+	}
+	if len(values) == 0 {
+		return "(empty)"
+	}
+	return "e.g. " + strings.Join(values, ", ")
+}
+
+// previewTransactions parses sample with format and renders the first
+// transactions, or the parse error.
+func previewTransactions(format csvstatement.Format, sample []byte) string {
+	if len(format.ColumnMappings) == 0 {
+		return "Map at least one column to see a preview."
+	}
+
+	parser := csvstatement.NewParser(format)
+	parser.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	stmt, err := parser.Parse(bytes.NewReader(sample))
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+
+	var b strings.Builder
+	for i, txn := range stmt.Transactions {
+		if i == wizardPreviewRows {
+			break
+		}
+		fmt.Fprintln(&b, previewLine(txn, format))
+	}
+	return b.String()
+}
+
+func previewLine(txn domain.Transaction, format csvstatement.Format) string {
+	exponent := money.Exponent(cmp.Or(txn.Currency, format.Currency))
+	return fmt.Sprintf("%s  %s  %s  %s",
+		txn.Date.Format(time.DateOnly), money.Format(txn.Amount, exponent, '.'), txn.CounterpartName, txn.Description)
+}
+
+// saveLayout writes format as a YAML layout to path, creating the directory
+// if needed.
+func saveLayout(path string, format csvstatement.Format, force bool) error {
+	data, err := csvstatement.MarshalLayout(format)
+	if err != nil {
+		return fmt.Errorf("failed to encode layout: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create layout directory: %v", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("layout file %s already exists, use --force to overwrite", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save layout: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save layout: %v", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fincli/internal/csvstatement"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_saveLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layouts", "mybank.yaml")
+	format := csvstatement.Format{Id: "mybank", Delimiter: ';'}
+
+	require.NoError(t, saveLayout(path, format, false))
+	assert.ErrorContains(t, saveLayout(path, format, false), "already exists")
+	require.NoError(t, saveLayout(path, format, true))
+
+	got, err := csvstatement.ReadLayoutFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "mybank", got.Id)
+	assert.Equal(t, ';', got.Delimiter)
+}
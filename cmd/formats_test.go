@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bytes"
+	"fincli/internal/csvstatement"
+	"fincli/internal/iostreams"
+	"fincli/internal/ofx"
+	"fincli/internal/statement"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRegistry() *csvstatement.FormatRegistry {
+	return &csvstatement.FormatRegistry{
+		"both": {
+			Id: "both", Delimiter: ',', HasHeader: true, DateFormat: "2006-01-02",
+			DecimalSeparator: '.', Source: "builtin:both.yaml",
+			ColumnMappings: []csvstatement.TransactionColumn{
+				{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+				{Name: "Amount", Kind: csvstatement.FieldInflow, Pos: 2},
+			},
+		},
+		"readonly": {
+			Id: "readonly", Delimiter: ';', HasHeader: true, DateFormat: "2006-01-02",
+			DecimalSeparator: ',', Source: "/layouts/readonly.yaml",
+			ColumnMappings: []csvstatement.TransactionColumn{
+				{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+				{Name: "Beløp", Kind: csvstatement.FieldInflow, Pos: 3},
+			},
+		},
+	}
+}
+
+func testCodecs() statement.Registry {
+	return statement.Registry{
+		"ofx": {
+			Id:          "ofx",
+			Description: "Open Financial Exchange",
+			NewReader:   func() statement.Reader { return ofx.NewReader() },
+			NewWriter:   func() statement.Writer { return ofx.NewWriter() },
+		},
+	}
+}
+
+func Test_formatsListRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &FormatsListOptions{
+		IO:       &iostreams.IOStreams{Out: out},
+		Registry: testRegistry(),
+		Codecs:   testCodecs(),
+	}
+
+	require.NoError(t, formatsListRun(opts))
+
+	want := "ID        DIRECTION   SOURCE\n" +
+		"both      read/write  builtin:both.yaml\n" +
+		"ofx       read/write  builtin\n" +
+		"readonly  read        /layouts/readonly.yaml\n"
+	assert.Equal(t, want, out.String())
+}
+
+func Test_formatsShowRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &FormatsShowOptions{
+		IO:       &iostreams.IOStreams{Out: out},
+		Registry: testRegistry(),
+		Codecs:   testCodecs(),
+		Id:       "readonly",
+	}
+
+	require.NoError(t, formatsShowRun(opts))
+	assert.Regexp(t, `Delimiter:\s+';'\n`, out.String())
+	assert.Contains(t, out.String(), "  3    Beløp  inflow\n")
+
+	out.Reset()
+	opts.Id = "ofx"
+	require.NoError(t, formatsShowRun(opts))
+	assert.Regexp(t, `Description:\s+Open Financial Exchange\n`, out.String())
+
+	opts.Id = "unknown"
+	assert.Error(t, formatsShowRun(opts))
+}
+
+func Test_formatsValidateRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		wantsErr bool
+		wantsOut []string
+	}{
+		{
+			name: "valid layout",
+			layout: `
+id: ok
+columns:
+  - {name: Date, kind: date, pos: 1}
+  - {name: Out, kind: outflow, pos: 2}
+`,
+			wantsOut: []string{"layout 'ok' supports read/write"},
+		},
+		{
+			name: "duplicate positions",
+			layout: `
+id: dup
+columns:
+  - {name: Date, kind: date, pos: 1}
+  - {name: In, kind: inflow, pos: 1}
+`,
+			wantsOut: []string{
+				`columns ["Date" "In"] share position 1 (prevents write)`,
+				"no column at position 2",
+				"layout 'dup' supports read",
+			},
+		},
+		{
+			name: "missing date and amount",
+			layout: `
+id: bad
+columns:
+  - {name: Memo, kind: memo, pos: 1}
+`,
+			wantsErr: true,
+			wantsOut: []string{
+				"no column of kind 'date', 'booking_date' or 'value_date'",
+				"no column of kind 'inflow', 'outflow' or 'amount'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "layout.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.layout), 0o644))
+
+			out := new(bytes.Buffer)
+			opts := &FormatsValidateOptions{
+				IO:       &iostreams.IOStreams{Out: out},
+				FilePath: path,
+			}
+
+			err := formatsValidateRun(opts)
+			if tt.wantsErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			for _, want := range tt.wantsOut {
+				assert.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
+
+func Test_previewTransactions(t *testing.T) {
+	nok := csvstatement.Format{
+		Delimiter: ';', HasHeader: true, DateFormat: "02.01.2006", DecimalSeparator: ',',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Ut", Kind: csvstatement.FieldOutflow, Pos: 2},
+			{Name: "Tekst", Kind: csvstatement.FieldMemo, Pos: 3},
+		},
+	}
+	wrongDate := nok
+	wrongDate.DateFormat = time.DateOnly
+	unmapped := nok
+	unmapped.ColumnMappings = nil
+	currencies := csvstatement.Format{
+		Delimiter: ';', HasHeader: true, DateFormat: time.DateOnly, DecimalSeparator: '.', Currency: "JPY",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+			{Name: "Currency", Kind: csvstatement.FieldCurrency, Pos: 3},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		format     csvstatement.Format
+		sample     string
+		want       string
+		wantsMatch string
+	}{
+		{
+			name:   "preview",
+			format: nok,
+			sample: "Dato;Ut;Tekst\n31.01.2025;12,34;Groceries\n",
+			want:   "2025-01-31  -12.34    Groceries\n",
+		},
+		{
+			name:       "parse error",
+			format:     wrongDate,
+			sample:     "Dato;Ut;Tekst\n31.01.2025;12,34;Groceries\n",
+			wantsMatch: "could not parse date",
+		},
+		{
+			name:       "no columns",
+			format:     unmapped,
+			sample:     "Dato;Ut;Tekst\n31.01.2025;12,34;Groceries\n",
+			wantsMatch: "Map at least one column",
+		},
+		{
+			name:   "decimals of the currency",
+			format: currencies,
+			sample: "Date;Amount;Currency\n2025-01-31;-1234;\n2025-02-01;-1.234;BHD\n",
+			want:   "2025-01-31  -1234    \n2025-02-01  -1.234    \n",
+		},
+		{
+			name:   "first rows only",
+			format: nok,
+			sample: "Dato;Ut;Tekst\n" + strings.Repeat("31.01.2025;1,00;Row\n", wizardPreviewRows+2),
+			want:   strings.Repeat("2025-01-31  -1.00    Row\n", wizardPreviewRows),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := previewTransactions(tt.format, []byte(tt.sample))
+			if tt.wantsMatch != "" {
+				assert.Contains(t, got, tt.wantsMatch)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_mappedKinds(t *testing.T) {
+	cols := []csvstatement.TransactionColumn{
+		{Name: "Tekst", Kind: csvstatement.FieldMemo, Pos: 1},
+		{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 3},
+		{Name: "Optional", Kind: csvstatement.FieldPayee, Pos: 0},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []csvstatement.FieldKind
+	}{
+		{
+			name: "all columns",
+			n:    3,
+			want: []csvstatement.FieldKind{csvstatement.FieldMemo, fieldSkip, csvstatement.FieldDate},
+		},
+		{
+			// Guessed with ';' as the delimiter, but the sample was split with ','.
+			name: "fewer columns",
+			n:    1,
+			want: []csvstatement.FieldKind{csvstatement.FieldMemo},
+		},
+		{
+			name: "no columns",
+			n:    0,
+			want: []csvstatement.FieldKind{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mappedKinds(cols, tt.n))
+		})
+	}
+}
+
+func Test_validateLayoutId(t *testing.T) {
+	tests := []struct {
+		id       string
+		wantsErr string
+	}{
+		{id: "mybank"},
+		{id: "my-bank.2025"},
+		{id: "", wantsErr: "must not be empty"},
+		{id: "my bank", wantsErr: "must not contain"},
+		{id: "layouts/mybank", wantsErr: "must not contain"},
+		{id: `layouts\mybank`, wantsErr: "must not contain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := validateLayoutId(tt.id)
+			if tt.wantsErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantsErr)
+		})
+	}
+}
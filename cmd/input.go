@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// stdinPath is the path argument that stands for standard input.
+const stdinPath = "-"
+
+// displayPath returns path as it is shown in messages.
+func displayPath(path string) string {
+	if path == stdinPath {
+		return "<stdin>"
+	}
+	return path
+}
+
+// expandPaths returns the statement files given by the path arguments. An
+// argument may be a glob pattern, which must match at least one file, or a
+// directory, which stands for the regular files in it that are not hidden.
+// Each file is returned once, in the order of the arguments. The argument
+// stdinPath is kept as it is.
+func expandPaths(args []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == stdinPath {
+			add(arg)
+			continue
+		}
+
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob pattern %s: %v", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				// A missing file is reported when it is opened.
+				add(match)
+				continue
+			}
+
+			files, err := dirFiles(match)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 && len(matches) == 1 {
+				return nil, fmt.Errorf("no files in directory %s", match)
+			}
+			for _, file := range files {
+				add(file)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// dirFiles returns the paths of the regular files in dir that are not hidden,
+// sorted by name.
+func dirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %v", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || !entry.Type().IsRegular() {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	slices.Sort(files)
+	return files, nil
+}
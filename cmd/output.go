@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// outputPathData holds the fields available in an output path template.
+type outputPathData struct {
+	Dir  string // Directory of the input file.
+	Name string // File name of the input file, such as "january.csv".
+	Stem string // File name without extension, such as "january".
+	Ext  string // Extension of the input file, such as ".csv".
+
+	FromFormat string
+	ToFormat   string
+}
+
+// outputPath returns the output path given by the template tmpl for the input
+// file inputPath. A path without template actions is returned as is.
+func outputPath(tmpl, inputPath, fromFormat, toFormat string) (string, error) {
+	if !strings.Contains(tmpl, "{{") {
+		return tmpl, nil
+	}
+
+	t, err := template.New("output").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path template: %v", err)
+	}
+
+	name := filepath.Base(inputPath)
+	ext := filepath.Ext(name)
+	data := outputPathData{
+		Dir:        filepath.Dir(inputPath),
+		Name:       name,
+		Stem:       strings.TrimSuffix(name, ext),
+		Ext:        ext,
+		FromFormat: fromFormat,
+		ToFormat:   toFormat,
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid output path template: %v", err)
+	}
+	if buf.Len() == 0 {
+		return "", errors.New("output path template gives an empty path")
+	}
+	return buf.String(), nil
+}
+
+// writeFileAtomic calls write with a temporary file in the directory of path,
+// and moves the file to path once write returns without error. An existing
+// file at path is only replaced if force is set. If write fails, path is left
+// as it was.
+func writeFileAtomic(path string, force bool, write func(io.Writer) error) (err error) {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("output file %s already exists, use --force to overwrite", path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to check output file: %v", err)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+
+	if !force {
+		// The file may have been created while converting.
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("output file %s already exists, use --force to overwrite", path)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
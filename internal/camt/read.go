@@ -0,0 +1,438 @@
+// Package camt reads bank statements in the ISO 20022 cash management XML
+// formats: camt.053 (account statement), camt.054 (debit/credit notification)
+// and camt.052 (account report).
+//
+// The elements are matched by name regardless of namespace, so the different
+// versions of the messages are all read the same way.
+package camt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fincli/internal/charset"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"time"
+)
+
+// Message names, as they appear in the namespace of the document.
+const (
+	Camt052 = "camt.052"
+	Camt053 = "camt.053"
+	Camt054 = "camt.054"
+)
+
+// Reader reads the entries (Ntry) of camt statements as transactions. It
+// implements [statement.Reader], [statement.Detector] and
+// [statement.Summarizer].
+type Reader struct {
+	// Message is the camt message detected by Detect, such as [Camt053].
+	// All reads any of them.
+	Message string
+
+	fileName  string
+	summaries []statement.Summary
+}
+
+func NewReader(message string) *Reader {
+	return &Reader{Message: message}
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (r *Reader) SetFileName(name string) {
+	r.fileName = name
+}
+
+// Detect reports whether sample is an XML document of the reader's message.
+func (r *Reader) Detect(sample []byte) bool {
+	return bytes.Contains(sample, []byte("urn:iso:std:iso:20022:tech:xsd:"+r.Message))
+}
+
+// Summaries returns the account, and the opening and closing balances, of
+// each statement (Stmt, Ntfctn or Rpt) read by All.
+func (r *Reader) Summaries() []statement.Summary {
+	return r.summaries
+}
+
+// All returns an iterator over the transactions in source.
+//
+// An entry with transaction details (TxDtls) for several transactions, each
+// with its own amount, is read as one transaction per detail; otherwise each
+// entry is one transaction. Entries that are pending or for information only
+// are left out.
+func (r *Reader) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		r.summaries = nil
+		decoder := xml.NewDecoder(source)
+		decoder.Strict = false
+		decoder.CharsetReader = charsetReader
+
+		var (
+			summary *statement.Summary
+			depth   int // Depth below the current statement element.
+		)
+		for {
+			tok, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(domain.Transaction{}, fmt.Errorf("could not read camt: %w", err))
+				return
+			}
+
+			switch el := tok.(type) {
+			case xml.StartElement:
+				if summary == nil {
+					switch el.Name.Local {
+					case "Stmt", "Ntfctn", "Rpt":
+						r.summaries = append(r.summaries, statement.Summary{})
+						summary = &r.summaries[len(r.summaries)-1]
+						depth = 0
+					}
+					continue
+				}
+
+				depth++
+				if depth > 1 {
+					continue
+				}
+				line, _ := decoder.InputPos()
+				switch el.Name.Local {
+				case "Id":
+					err = decoder.DecodeElement(&summary.Id, &el)
+				case "Acct":
+					err = decodeAccount(decoder, &el, summary)
+				case "Bal":
+					err = decodeBalance(decoder, &el, summary)
+				case "Ntry":
+					var e entry
+					if err = decoder.DecodeElement(&e, &el); err != nil {
+						break
+					}
+					for txn, recErr := range e.transactions(summary.Currency) {
+						if recErr != nil {
+							recErr.File, recErr.Line = r.fileName, line
+							if !yield(domain.Transaction{}, recErr) {
+								return
+							}
+							continue
+						}
+						txn.Account = summary.Account
+						if !yield(txn, nil) {
+							return
+						}
+					}
+				default:
+					err = decoder.Skip()
+				}
+				if err != nil {
+					yield(domain.Transaction{}, fmt.Errorf("could not read camt: %w", err))
+					return
+				}
+				depth--
+			case xml.EndElement:
+				if summary == nil {
+					continue
+				}
+				if depth == 0 {
+					summary = nil
+					continue
+				}
+				depth--
+			}
+		}
+	}
+}
+
+func decodeAccount(decoder *xml.Decoder, el *xml.StartElement, summary *statement.Summary) error {
+	var acct struct {
+		IBAN  string `xml:"Id>IBAN"`
+		Other string `xml:"Id>Othr>Id"`
+		Ccy   string `xml:"Ccy"`
+	}
+	if err := decoder.DecodeElement(&acct, el); err != nil {
+		return err
+	}
+	summary.Account = strings.TrimSpace(acct.IBAN)
+	if summary.Account == "" {
+		summary.Account = strings.TrimSpace(acct.Other)
+	}
+	summary.Currency = strings.TrimSpace(acct.Ccy)
+	return nil
+}
+
+func decodeBalance(decoder *xml.Decoder, el *xml.StartElement, summary *statement.Summary) error {
+	var bal struct {
+		Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+		Amt       amount     `xml:"Amt"`
+		CdtDbtInd string     `xml:"CdtDbtInd"`
+		Dt        dateOrTime `xml:"Dt"`
+	}
+	if err := decoder.DecodeElement(&bal, el); err != nil {
+		return err
+	}
+
+	var dest **statement.Balance
+	switch strings.TrimSpace(bal.Code) {
+	case "OPBD", "PRCD":
+		if summary.Opening != nil {
+			// Prefer the opening booked balance over the previous
+			// closing balance.
+			return nil
+		}
+		dest = &summary.Opening
+	case "CLBD":
+		dest = &summary.Closing
+	default:
+		return nil
+	}
+
+	value, err := bal.Amt.parse(bal.CdtDbtInd)
+	if err != nil {
+		return fmt.Errorf("invalid balance: %v", err)
+	}
+	date, err := bal.Dt.parse()
+	if err != nil {
+		return fmt.Errorf("invalid balance: %v", err)
+	}
+	*dest = &statement.Balance{Date: date, Amount: value, Currency: bal.Amt.currency()}
+	return nil
+}
+
+// entry is an Ntry element.
+type entry struct {
+	NtryRef      string     `xml:"NtryRef"`
+	Amt          amount     `xml:"Amt"`
+	CdtDbtInd    string     `xml:"CdtDbtInd"`
+	Sts          status     `xml:"Sts"`
+	BookgDt      dateOrTime `xml:"BookgDt"`
+	ValDt        dateOrTime `xml:"ValDt"`
+	AcctSvcrRef  string     `xml:"AcctSvcrRef"`
+	TxDtls       []details  `xml:"NtryDtls>TxDtls"`
+	AddtlNtryInf string     `xml:"AddtlNtryInf"`
+}
+
+// details is a TxDtls element.
+type details struct {
+	AcctSvcrRef string   `xml:"Refs>AcctSvcrRef"`
+	EndToEndId  string   `xml:"Refs>EndToEndId"`
+	Amt         amount   `xml:"Amt"`
+	TxAmt       amount   `xml:"AmtDtls>TxAmt>Amt"`
+	CdtDbtInd   string   `xml:"CdtDbtInd"`
+	Debtor      party    `xml:"RltdPties>Dbtr"`
+	Creditor    party    `xml:"RltdPties>Cdtr"`
+	Ustrd       []string `xml:"RmtInf>Ustrd"`
+	Strd        []struct {
+		Ref         string   `xml:"CdtrRefInf>Ref"`
+		AddtlRmtInf []string `xml:"AddtlRmtInf"`
+	} `xml:"RmtInf>Strd"`
+	AddtlTxInf string `xml:"AddtlTxInf"`
+}
+
+// party is a Dbtr or Cdtr element. The name is in Nm before camt.053.001.08,
+// and in Pty>Nm from then on.
+type party struct {
+	Nm    string `xml:"Nm"`
+	PtyNm string `xml:"Pty>Nm"`
+}
+
+func (p party) name() string {
+	if p.PtyNm != "" {
+		return strings.TrimSpace(p.PtyNm)
+	}
+	return strings.TrimSpace(p.Nm)
+}
+
+// status is a Sts element, which holds the code directly before
+// camt.053.001.08, and in Cd from then on.
+type status struct {
+	Value string `xml:",chardata"`
+	Cd    string `xml:"Cd"`
+}
+
+func (s status) code() string {
+	if s.Cd != "" {
+		return strings.TrimSpace(s.Cd)
+	}
+	return strings.TrimSpace(s.Value)
+}
+
+type amount struct {
+	Value string `xml:",chardata"`
+	Ccy   string `xml:"Ccy,attr"`
+}
+
+func (a amount) currency() string {
+	return strings.ToUpper(strings.TrimSpace(a.Ccy))
+}
+
+// parse returns the amount in minor units, negative if indicator is DBIT.
+func (a amount) parse(indicator string) (int, error) {
+	parser := money.Parser{DecimalSeparator: '.', Exponent: money.Exponent(a.currency())}
+	value, err := parser.Parse(a.Value)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.TrimSpace(indicator) {
+	case "DBIT":
+		return -value, nil
+	case "CRDT":
+		return value, nil
+	}
+	return 0, fmt.Errorf("invalid credit/debit indicator '%s'", indicator)
+}
+
+// dateOrTime is an element with either a Dt or a DtTm.
+type dateOrTime struct {
+	Dt   string `xml:"Dt"`
+	DtTm string `xml:"DtTm"`
+}
+
+func (d dateOrTime) empty() bool {
+	return d.Dt == "" && d.DtTm == ""
+}
+
+func (d dateOrTime) parse() (time.Time, error) {
+	if d.DtTm != "" {
+		value := strings.TrimSpace(d.DtTm)
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02T15:04:05.999999999", value); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("could not parse date and time '%s'", value)
+	}
+	value := strings.TrimSpace(d.Dt)
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	// ISODate may have a time zone, as in "2025-01-02+01:00".
+	if t, err := time.Parse("2006-01-02Z07:00", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+}
+
+// transactions returns an iterator over the transactions of the entry.
+func (e entry) transactions(defaultCurrency string) iter.Seq2[domain.Transaction, *statement.RecordError] {
+	return func(yield func(domain.Transaction, *statement.RecordError) bool) {
+		switch e.Sts.code() {
+		case "PDNG", "INFO":
+			return
+		}
+
+		split := len(e.TxDtls) > 1
+		for _, d := range e.TxDtls {
+			if d.Amt.Value == "" && d.TxAmt.Value == "" {
+				split = false
+			}
+		}
+		if !split {
+			var d details
+			if len(e.TxDtls) > 0 {
+				d = e.TxDtls[0]
+			}
+			d.Amt, d.TxAmt, d.CdtDbtInd = e.Amt, amount{}, e.CdtDbtInd
+			yield(e.transaction(d, defaultCurrency))
+			return
+		}
+		for _, d := range e.TxDtls {
+			if d.Amt.Value == "" {
+				d.Amt = d.TxAmt
+			}
+			if d.CdtDbtInd == "" {
+				d.CdtDbtInd = e.CdtDbtInd
+			}
+			if !yield(e.transaction(d, defaultCurrency)) {
+				return
+			}
+		}
+	}
+}
+
+func (e entry) transaction(d details, defaultCurrency string) (domain.Transaction, *statement.RecordError) {
+	txn := domain.Transaction{
+		Id:         firstOf(d.AcctSvcrRef, e.AcctSvcrRef, endToEndId(d.EndToEndId), e.NtryRef),
+		EndToEndId: endToEndId(d.EndToEndId),
+		Currency:   firstOf(d.Amt.currency(), strings.ToUpper(defaultCurrency)),
+	}
+
+	var err error
+	if txn.Amount, err = d.Amt.parse(d.CdtDbtInd); err != nil {
+		return txn, &statement.RecordError{Column: "Amt", Value: d.Amt.Value, Err: err}
+	}
+
+	// The counterpart is the creditor of payments out of the account, and
+	// the debtor of payments into it.
+	if txn.Amount < 0 {
+		txn.CounterpartName = d.Creditor.name()
+	} else {
+		txn.CounterpartName = d.Debtor.name()
+	}
+
+	var remittance []string
+	for _, s := range d.Ustrd {
+		remittance = append(remittance, strings.TrimSpace(s))
+	}
+	for _, s := range d.Strd {
+		if ref := strings.TrimSpace(s.Ref); ref != "" {
+			remittance = append(remittance, ref)
+		}
+		for _, info := range s.AddtlRmtInf {
+			remittance = append(remittance, strings.TrimSpace(info))
+		}
+	}
+	txn.Description = firstOf(strings.Join(remittance, " "), strings.TrimSpace(d.AddtlTxInf), strings.TrimSpace(e.AddtlNtryInf))
+
+	if !e.BookgDt.empty() {
+		if txn.BookingDate, err = e.BookgDt.parse(); err != nil {
+			return txn, &statement.RecordError{Column: "BookgDt", Value: e.BookgDt.Dt + e.BookgDt.DtTm, Err: err}
+		}
+	}
+	if !e.ValDt.empty() {
+		if txn.ValueDate, err = e.ValDt.parse(); err != nil {
+			return txn, &statement.RecordError{Column: "ValDt", Value: e.ValDt.Dt + e.ValDt.DtTm, Err: err}
+		}
+	}
+	txn.Date = txn.BookingDate
+	if txn.Date.IsZero() {
+		txn.Date = txn.ValueDate
+	}
+	if txn.Date.IsZero() {
+		return txn, &statement.RecordError{Column: "BookgDt", Err: errors.New("entry has neither booking date nor value date")}
+	}
+	return txn, nil
+}
+
+// charsetReader decodes documents in encodings other than UTF-8, such as
+// ISO-8859-1, by the encoding of their XML declaration.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	return charset.NewReader(input, label)
+}
+
+// endToEndId returns id, unless it is the placeholder used when the payer
+// gave no end-to-end identification.
+func endToEndId(id string) string {
+	if strings.TrimSpace(id) == "NOTPROVIDED" {
+		return ""
+	}
+	return id
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
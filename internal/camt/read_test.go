@@ -0,0 +1,198 @@
+package camt_test
+
+import (
+	"errors"
+	"fincli/internal/camt"
+	"fincli/internal/domain"
+	"fincli/internal/statement"
+	"fincli/internal/statement/statementtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG1</MsgId><CreDtTm>2025-01-31T23:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-2025-01</Id>
+      <Acct><Id><IBAN>NO9386011117947</IBAN></Id><Ccy>NOK</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="NOK">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2025-01-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="NOK">1387.66</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2025-01-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="NOK">112.34</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-02</Dt></BookgDt>
+        <ValDt><Dt>2025-01-03</Dt></ValDt>
+        <AcctSvcrRef>REF1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+          <RltdPties><Cdtr><Nm>Kiwi Oslo</Nm></Cdtr></RltdPties>
+          <RmtInf><Strd><CdtrRefInf><Ref>123456789</Ref></CdtrRefInf></Strd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="NOK">500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-15</Dt></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="NOK">200.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Dbtr><Nm>Ola</Nm></Dbtr></RltdPties>
+            <RmtInf><Ustrd>Lunch</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E2E-2</EndToEndId></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="NOK">300.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Dbtr><Nm>Kari</Nm></Dbtr></RltdPties>
+            <RmtInf><Ustrd>Dinner</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="NOK">1.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2025-01-31</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestReader_Camt053(t *testing.T) {
+	date := func(day int) time.Time {
+		return time.Date(2025, time.January, day, 0, 0, 0, 0, time.UTC)
+	}
+	want := []domain.Transaction{
+		{
+			Id: "REF1", Date: date(2), BookingDate: date(2), ValueDate: date(3),
+			CounterpartName: "Kiwi Oslo", Description: "123456789", Amount: -11234, Currency: "NOK", Account: "NO9386011117947",
+		},
+		{
+			Id: "E2E-1", EndToEndId: "E2E-1", Date: date(15), BookingDate: date(15),
+			CounterpartName: "Ola", Description: "Lunch", Amount: 20000, Currency: "NOK", Account: "NO9386011117947",
+		},
+		{
+			Id: "E2E-2", EndToEndId: "E2E-2", Date: date(15), BookingDate: date(15),
+			CounterpartName: "Kari", Description: "Dinner", Amount: 30000, Currency: "NOK", Account: "NO9386011117947",
+		},
+	}
+
+	reader := camt.NewReader(camt.Camt053)
+	got := statementtest.Collect(t, reader.All(strings.NewReader(camt053)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n\t%+v\nwant:\n\t%+v", got, want)
+	}
+
+	wantSummaries := []statement.Summary{{
+		Id:       "STMT-2025-01",
+		Account:  "NO9386011117947",
+		Currency: "NOK",
+		Opening:  &statement.Balance{Date: date(1), Amount: 100000, Currency: "NOK"},
+		Closing:  &statement.Balance{Date: date(31), Amount: 138766, Currency: "NOK"},
+	}}
+	if summaries := reader.Summaries(); !reflect.DeepEqual(summaries, wantSummaries) {
+		t.Errorf("summaries:\ngot:\t%+v\nwant:\t%+v", summaries, wantSummaries)
+	}
+}
+
+func TestReader_EndToEndIdAndCharset(t *testing.T) {
+	// Encoded as ISO-8859-1, in which "ø" is the single byte 0xF8.
+	data := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt>
+  <Ntry>
+    <Amt Ccy="NOK">250.00</Amt>
+    <CdtDbtInd>CRDT</CdtDbtInd>
+    <Sts>BOOK</Sts>
+    <BookgDt><Dt>2025-01-20</Dt></BookgDt>
+    <AcctSvcrRef>BANK-REF</AcctSvcrRef>
+    <NtryDtls><TxDtls>
+      <Refs><EndToEndId>INV-2025-17</EndToEndId></Refs>
+      <RltdPties><Dbtr><Nm>Bj` + "\xf8" + `rn</Nm></Dbtr></RltdPties>
+    </TxDtls></NtryDtls>
+  </Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+
+	got := statementtest.Collect(t, camt.NewReader(camt.Camt053).All(strings.NewReader(data)))
+	if len(got) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(got))
+	}
+	if txn := got[0]; txn.Id != "BANK-REF" || txn.EndToEndId != "INV-2025-17" || txn.CounterpartName != "Bjørn" {
+		t.Errorf("unexpected transaction: %+v", txn)
+	}
+}
+
+func TestReader_Camt054(t *testing.T) {
+	data := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.08">
+<BkToCstmrDbtCdtNtfctn><Ntfctn>
+  <Acct><Id><Othr><Id>12345678901</Id></Othr></Id></Acct>
+  <Ntry>
+    <Amt Ccy="EUR">9.99</Amt>
+    <CdtDbtInd>DBIT</CdtDbtInd>
+    <Sts><Cd>BOOK</Cd></Sts>
+    <BookgDt><DtTm>2025-02-01T10:30:00+01:00</DtTm></BookgDt>
+    <NtryDtls><TxDtls>
+      <RltdPties><Cdtr><Pty><Nm>Streaming Inc</Nm></Pty></Cdtr></RltdPties>
+    </TxDtls></NtryDtls>
+    <AddtlNtryInf>Card payment</AddtlNtryInf>
+  </Ntry>
+  <Ntry>
+    <Amt Ccy="EUR">x</Amt>
+    <CdtDbtInd>DBIT</CdtDbtInd>
+    <BookgDt><Dt>2025-02-02</Dt></BookgDt>
+  </Ntry>
+</Ntfctn></BkToCstmrDbtCdtNtfctn>
+</Document>`
+
+	reader := camt.NewReader(camt.Camt054)
+	reader.SetFileName("notification.xml")
+	if !reader.Detect([]byte(data)) {
+		t.Error("expected camt.054 to be detected")
+	}
+	if camt.NewReader(camt.Camt053).Detect([]byte(data)) {
+		t.Error("expected camt.054 not to be detected as camt.053")
+	}
+
+	var (
+		got    []domain.Transaction
+		recErr *statement.RecordError
+	)
+	for txn, err := range reader.All(strings.NewReader(data)) {
+		if err != nil {
+			if !errors.As(err, &recErr) {
+				t.Fatalf("expected *statement.RecordError, got %v", err)
+			}
+			continue
+		}
+		got = append(got, txn)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(got))
+	}
+	cet := time.FixedZone("", 3600)
+	if txn := got[0]; txn.CounterpartName != "Streaming Inc" || txn.Description != "Card payment" ||
+		txn.Amount != -999 || txn.Currency != "EUR" || !txn.Date.Equal(time.Date(2025, time.February, 1, 10, 30, 0, 0, cet)) {
+		t.Errorf("unexpected transaction: %+v", txn)
+	}
+	if recErr == nil || recErr.File != "notification.xml" || recErr.Column != "Amt" || recErr.Line != 14 {
+		t.Errorf("unexpected error: %v", recErr)
+	}
+	if summaries := reader.Summaries(); len(summaries) != 1 || summaries[0].Account != "12345678901" {
+		t.Errorf("unexpected summaries: %+v", summaries)
+	}
+}
@@ -0,0 +1,56 @@
+// Package charset decodes statements in legacy character encodings, such as
+// Windows-1252 and UTF-16, to UTF-8, and encodes UTF-8 back to them.
+package charset
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Lookup returns the encoding with the given IANA name or alias, such as
+// "windows-1252", "ISO-8859-1" or "UTF-16LE". The empty name is UTF-8.
+func Lookup(name string) (encoding.Encoding, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unknown character encoding '%s'", name)
+	}
+	return enc, nil
+}
+
+// NewReader returns a reader that decodes source from the named encoding to
+// UTF-8. A byte order mark at the start of source takes precedence over the
+// name, and is removed.
+func NewReader(source io.Reader, name string) (io.Reader, error) {
+	enc, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(source, unicode.BOMOverride(enc.NewDecoder())), nil
+}
+
+// NewWriter returns a writer that encodes UTF-8 to the named encoding before
+// writing to target. It must be closed to write any buffered bytes. Text that
+// cannot be represented in the encoding gives an error.
+func NewWriter(target io.Writer, name string) (io.WriteCloser, error) {
+	enc, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewWriter(target, enc.NewEncoder()), nil
+}
+
+// IsUTF8 reports whether name is UTF-8, or empty.
+func IsUTF8(name string) bool {
+	enc, err := Lookup(name)
+	return err == nil && enc == unicode.UTF8
+}
@@ -0,0 +1,63 @@
+package charset_test
+
+import (
+	"bytes"
+	"fincli/internal/charset"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		data     string
+		want     string
+	}{
+		{name: "utf-8", data: "Inn på konto", want: "Inn på konto"},
+		{name: "utf-8 bom", data: "\xef\xbb\xbfInn på konto", want: "Inn på konto"},
+		{name: "iso-8859-1", encoding: "ISO-8859-1", data: "Inn p\xe5 konto", want: "Inn på konto"},
+		{name: "utf-16be bom", encoding: "windows-1252", data: "\xfe\xff\x00p\x00\xe5", want: "på"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := charset.NewReader(strings.NewReader(tt.data), tt.encoding)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := charset.NewWriter(&buf, "windows-1252")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.WriteString(w, "Inn på konto")
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Inn p\xe5 konto"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := charset.Lookup("klingon"); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+	if !charset.IsUTF8("") || !charset.IsUTF8("utf-8") || charset.IsUTF8("UTF-16LE") {
+		t.Error("unexpected result of IsUTF8")
+	}
+}
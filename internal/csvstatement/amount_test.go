@@ -0,0 +1,167 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
+	"fincli/internal/statement"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_SignedAmount(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ';', HasHeader: true, DateFormat: time.DateOnly,
+		DecimalSeparator: ',', ThousandsSeparator: '.',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+		},
+	}
+	csvData := "Date;Amount\n" +
+		"2025-01-01;-12,34\n" +
+		"2025-01-02;(1.234,50)\n" +
+		"2025-01-03;99,00-\n" +
+		"2025-01-04;500,00\n"
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int{-1234, -123450, -9900, 50000}
+	if len(got.Transactions) != len(want) {
+		t.Fatalf("expected %d transactions, got %d", len(want), len(got.Transactions))
+	}
+	for i, amount := range want {
+		if got.Transactions[i].Amount != amount {
+			t.Errorf("transaction %d: got amount %d, want %d", i, got.Transactions[i].Amount, amount)
+		}
+	}
+}
+
+func TestParser_Direction(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly, DecimalSeparator: '.',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+			{Name: "Type", Kind: csvstatement.FieldDirection, Pos: 3,
+				DebitValues: []string{"Belastning"}, CreditValues: []string{"Innskudd"}},
+		},
+	}
+	csvData := "Date,Amount,Type\n" +
+		"2025-01-01,12.34,belastning\n" +
+		"2025-01-02,-5.00,Innskudd\n" +
+		"2025-01-03,7.00,\n" +
+		"2025-01-04,1.00,D\n"
+
+	parser := csvstatement.NewParser(format)
+	var amounts []int
+	var recErrs []*statement.RecordError
+	for txn, err := range statement.SkipRecordErrors(&recErrs)(parser.All(strings.NewReader(csvData))) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		amounts = append(amounts, txn.Amount)
+	}
+
+	want := []int{-1234, 500, 700}
+	if len(amounts) != len(want) {
+		t.Fatalf("expected amounts %v, got %v", want, amounts)
+	}
+	for i := range want {
+		if amounts[i] != want[i] {
+			t.Errorf("transaction %d: got amount %d, want %d", i, amounts[i], want[i])
+		}
+	}
+
+	if len(recErrs) != 1 || recErrs[0].Line != 5 || recErrs[0].Value != "D" {
+		t.Fatalf("expected unknown indicator on line 5 to be an error, got %v", recErrs)
+	}
+}
+
+func TestParser_DefaultDirectionValues(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ',', DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+			{Name: "DC", Kind: csvstatement.FieldDirection, Pos: 3},
+		},
+	}
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader("2025-01-01,1.00,DR\n2025-01-01,2.00,c\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Transactions[0].Amount != -100 || got.Transactions[1].Amount != 200 {
+		t.Errorf("unexpected amounts: %+v", got.Transactions)
+	}
+
+	_, err = csvstatement.NewParser(format).Parse(strings.NewReader("2025-01-01,1.00,X\n"))
+	var recErr *statement.RecordError
+	if !errors.As(err, &recErr) || recErr.Column != "DC" {
+		t.Errorf("expected RecordError for column DC, got %v", err)
+	}
+}
+
+func TestWriteStatement_SignedAmount(t *testing.T) {
+	statement := csvstatement.ParsedStatement{
+		Transactions: []domain.Transaction{
+			{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Amount: -1234},
+			{Date: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), Amount: 50000},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		columns []csvstatement.TransactionColumn
+		want    string
+	}{
+		{
+			name: "signed amount",
+			columns: []csvstatement.TransactionColumn{
+				{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+				{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+			},
+			want: "Date,Amount\n2025-01-01,-12.34\n2025-01-02,500.00\n",
+		},
+		{
+			name: "amount with direction",
+			columns: []csvstatement.TransactionColumn{
+				{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+				{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+				{Name: "DC", Kind: csvstatement.FieldDirection, Pos: 3},
+			},
+			want: "Date,Amount,DC\n2025-01-01,12.34,D\n2025-01-02,500.00,C\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := csvstatement.Format{
+				Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly, DecimalSeparator: '.',
+				ColumnMappings: tt.columns,
+			}
+
+			var out strings.Builder
+			if err := csvstatement.WriteStatement(&out, statement, format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+
+			roundTrip, err := csvstatement.NewParser(format).Parse(strings.NewReader(out.String()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, txn := range roundTrip.Transactions {
+				if txn.Amount != statement.Transactions[i].Amount {
+					t.Errorf("round trip of transaction %d: got %d, want %d", i, txn.Amount, statement.Transactions[i].Amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_BulderCategories(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, err := registry.Get("bulder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvData := "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;" +
+		"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori\n" +
+		"2025-01-01;;12,34;;;;;;Kiwi;;Mat og drikke;Dagligvarer\n" +
+		"2025-01-02;;100,00;;;;;;Vinmonopolet;;Mat og drikke;\n" +
+		"2025-01-03;;50,00;;;;;;Ukjent;;;\n"
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Mat og drikke:Dagligvarer", "Mat og drikke", ""}
+	if len(got.Transactions) != len(want) {
+		t.Fatalf("expected %d transactions, got %d", len(want), len(got.Transactions))
+	}
+	for i, txn := range got.Transactions {
+		if txn.Category != want[i] {
+			t.Errorf("transaction %d: category %q, want %q", i, txn.Category, want[i])
+		}
+	}
+}
+
+func Test_BulderToYnabWithCategoryColumn(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulder, _ := registry.Get("bulder")
+	ynab, _ := registry.Get("ynab")
+
+	// The built-in ynab layout has no category column, as YNAB does not
+	// import one. A user layout opts in by adding it.
+	ynab.ColumnMappings = append(slices.Clone(ynab.ColumnMappings),
+		csvstatement.TransactionColumn{Name: "Category", Kind: csvstatement.FieldCategory, Pos: 6})
+
+	csvData := "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;" +
+		"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori\n" +
+		"2025-01-01;;12,34;;;;;;Kiwi;;Mat og drikke;Dagligvarer\n"
+
+	var out strings.Builder
+	if err := csvstatement.Convert(strings.NewReader(csvData), &out, bulder, ynab); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Date,Payee,Memo,Inflow,Outflow,Category\n" +
+		"2025-01-01,,Kiwi,0.00,12.34,Mat og drikke:Dagligvarer\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func Test_CategoryTagsNotesRoundTrip(t *testing.T) {
+	format := csvstatement.NewFormat()
+	format.Delimiter = ';'
+	format.DateFormat = "2006-01-02"
+	format.DecimalSeparator = ','
+	format.ColumnMappings = []csvstatement.TransactionColumn{
+		{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+		{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+		{Name: "Category", Kind: csvstatement.FieldCategory, Pos: 3},
+		{Name: "Subcategory", Kind: csvstatement.FieldSubcategory, Pos: 4},
+		{Name: "Tags", Kind: csvstatement.FieldTags, Pos: 5},
+		{Name: "Notes", Kind: csvstatement.FieldNotes, Pos: 6},
+	}
+	if problems := format.Validate(); len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	csvData := "Date;Amount;Category;Subcategory;Tags;Notes\n" +
+		"2025-01-01;-12,34;Food;Groceries:Fruit;weekly, shared ,weekly;Split with Kari\n"
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(got.Transactions))
+	}
+	txn := got.Transactions[0]
+	if txn.Category != "Food:Groceries:Fruit" {
+		t.Errorf("category %q, want %q", txn.Category, "Food:Groceries:Fruit")
+	}
+	if want := []string{"weekly", "shared"}; !reflect.DeepEqual(txn.Tags, want) {
+		t.Errorf("tags %q, want %q", txn.Tags, want)
+	}
+	if txn.Notes != "Split with Kari" {
+		t.Errorf("notes %q, want %q", txn.Notes, "Split with Kari")
+	}
+
+	var out strings.Builder
+	if err := csvstatement.WriteStatement(&out, got, format); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Date;Amount;Category;Subcategory;Tags;Notes\n" +
+		"2025-01-01;-12,34;Food;Groceries:Fruit;weekly,shared;Split with Kari\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func Test_WriteCategoryWithoutSubcategoryColumn(t *testing.T) {
+	format := csvstatement.NewFormat()
+	format.Delimiter = ','
+	format.DateFormat = "2006-01-02"
+	format.DecimalSeparator = '.'
+	format.ColumnMappings = []csvstatement.TransactionColumn{
+		{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+		{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+		{Name: "Category", Kind: csvstatement.FieldCategory, Pos: 3},
+	}
+
+	csvData := "Date,Amount,Category\n2025-01-01,-12.34,Food:Groceries\n"
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out strings.Builder
+	if err := csvstatement.WriteStatement(&out, got, format); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != csvData {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), csvData)
+	}
+}
@@ -0,0 +1,161 @@
+package csvstatement
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// ComposedField sets a field of transactions from several columns of a
+// record, such as a memo made of a transaction type and a text column.
+type ComposedField struct {
+	// Kind is the field that is set, either FieldPayee or FieldMemo. It
+	// replaces the value of any column of the same kind.
+	Kind FieldKind `yaml:"kind"`
+	// Template is a text/template that gives the value of the field. The
+	// columns of the record are available by their header names, and by the
+	// names of the column mappings, such as in "{{.Type}}: {{.Tekst}}", or
+	// {{index . "Til konto"}} for names that are not identifiers. Missing
+	// columns are empty. Surrounding spaces are removed from the result.
+	Template string `yaml:"template"`
+	// Transforms are applied to the result of the template, in order.
+	Transforms []FieldTransform `yaml:"transforms,omitempty"`
+}
+
+// FieldTransform changes the value of a column or a composed field.
+type FieldTransform struct {
+	Op TransformOp `yaml:"op"`
+	// Pattern is the regular expression of TransformExtract and
+	// TransformReplace.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Replacement replaces the matches of Pattern with TransformReplace. It
+	// may refer to submatches, such as "$1".
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+// TransformOp is the operation of a FieldTransform.
+type TransformOp string
+
+const (
+	// TransformTrim removes surrounding spaces.
+	TransformTrim TransformOp = "trim"
+	// TransformSqueeze removes surrounding spaces, and replaces runs of
+	// spaces inside the value with a single space.
+	TransformSqueeze TransformOp = "squeeze"
+	// TransformTitle changes the value to title case, as in "Kiwi Majorstuen"
+	// for "KIWI MAJORSTUEN".
+	TransformTitle TransformOp = "title"
+	TransformLower TransformOp = "lower"
+	TransformUpper TransformOp = "upper"
+	// TransformExtract replaces the value with the first submatch of Pattern,
+	// or the whole match if Pattern has no submatches. Values that Pattern
+	// does not match are left as they are.
+	TransformExtract TransformOp = "extract"
+	// TransformReplace replaces the matches of Pattern with Replacement.
+	TransformReplace TransformOp = "replace"
+)
+
+// transformFunc is a compiled FieldTransform, or chain of them.
+type transformFunc func(string) string
+
+func (t FieldTransform) compile() (transformFunc, error) {
+	var pattern *regexp.Regexp
+	if t.Op == TransformExtract || t.Op == TransformReplace {
+		var err error
+		if pattern, err = regexp.Compile(t.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of transform '%s': %w", t.Op, err)
+		}
+	}
+
+	switch t.Op {
+	case TransformTrim:
+		return strings.TrimSpace, nil
+	case TransformSqueeze:
+		return func(s string) string { return strings.Join(strings.Fields(s), " ") }, nil
+	case TransformTitle:
+		caser := cases.Title(language.Und)
+		return caser.String, nil
+	case TransformLower:
+		return strings.ToLower, nil
+	case TransformUpper:
+		return strings.ToUpper, nil
+	case TransformExtract:
+		return func(s string) string {
+			match := pattern.FindStringSubmatch(s)
+			switch {
+			case match == nil:
+				return s
+			case len(match) > 1:
+				return match[1]
+			default:
+				return match[0]
+			}
+		}, nil
+	case TransformReplace:
+		return func(s string) string { return pattern.ReplaceAllString(s, t.Replacement) }, nil
+	default:
+		return nil, fmt.Errorf("unknown transform '%s'", t.Op)
+	}
+}
+
+// compileTransforms returns a function that applies transforms in order, or
+// nil if there are no transforms.
+func compileTransforms(transforms []FieldTransform) (transformFunc, error) {
+	if len(transforms) == 0 {
+		return nil, nil
+	}
+	funcs := make([]transformFunc, len(transforms))
+	for i, t := range transforms {
+		f, err := t.compile()
+		if err != nil {
+			return nil, err
+		}
+		funcs[i] = f
+	}
+	return func(s string) string {
+		for _, f := range funcs {
+			s = f(s)
+		}
+		return s
+	}, nil
+}
+
+// compiledField is a compiled ComposedField.
+type compiledField struct {
+	kind      FieldKind
+	template  *template.Template
+	transform transformFunc
+}
+
+func (f ComposedField) compile() (compiledField, error) {
+	if f.Kind != FieldPayee && f.Kind != FieldMemo {
+		return compiledField{}, fmt.Errorf("composed field has kind '%s', must be '%s' or '%s'", f.Kind, FieldPayee, FieldMemo)
+	}
+	tmpl, err := template.New(string(f.Kind)).Option("missingkey=zero").Parse(f.Template)
+	if err != nil {
+		return compiledField{}, fmt.Errorf("invalid template of composed field '%s': %w", f.Kind, err)
+	}
+	transform, err := compileTransforms(f.Transforms)
+	if err != nil {
+		return compiledField{}, fmt.Errorf("composed field '%s': %w", f.Kind, err)
+	}
+	return compiledField{kind: f.Kind, template: tmpl, transform: transform}, nil
+}
+
+// value returns the value of the field for a record with the given columns
+// by name.
+func (f compiledField) value(columns map[string]string) (string, error) {
+	var b strings.Builder
+	if err := f.template.Execute(&b, columns); err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(b.String())
+	if f.transform != nil {
+		value = f.transform(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,138 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_ComposedFields_Bulder(t *testing.T) {
+	csvData := "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;" +
+		"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori\n" +
+		"2025-01-01;;12,34;Kiwi Majorstuen;;;;Varekjøp;KIWI 123 MAJORSTUEN;;;\n" +
+		"2025-01-02;500,00;;;;Ola Nordmann;;Overføring;Middag;;;\n"
+
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, err := registry.Get("bulder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		fields []csvstatement.ComposedField
+		want   [][2]string
+	}{
+		{
+			name: "built-in",
+			want: [][2]string{
+				{"", "KIWI 123 MAJORSTUEN"},
+				{"", "Middag"},
+			},
+		},
+		{
+			name: "composed",
+			fields: []csvstatement.ComposedField{
+				{Kind: csvstatement.FieldPayee, Template: `{{or (index . "Til konto") (index . "Fra konto")}}`},
+				{Kind: csvstatement.FieldMemo, Template: "{{with .Type}}{{.}}: {{end}}{{.Tekst}}"},
+			},
+			want: [][2]string{
+				{"Kiwi Majorstuen", "Varekjøp: KIWI 123 MAJORSTUEN"},
+				{"Ola Nordmann", "Overføring: Middag"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := format
+			format.Fields = tt.fields
+			got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got.Transactions) != len(tt.want) {
+				t.Fatalf("expected %d transactions, got %d", len(tt.want), len(got.Transactions))
+			}
+			for i, txn := range got.Transactions {
+				if txn.CounterpartName != tt.want[i][0] || txn.Description != tt.want[i][1] {
+					t.Errorf("transaction %d: got payee %q and memo %q, want %q", i, txn.CounterpartName, txn.Description, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParser_Transforms(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:        ',',
+		HasHeader:        true,
+		DateFormat:       time.DateOnly,
+		DecimalSeparator: '.',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2, Transforms: []csvstatement.FieldTransform{
+				{Op: csvstatement.TransformReplace, Pattern: `^NOK\s*`},
+			}},
+			{Name: "Text", Kind: csvstatement.FieldMemo, Pos: 3, Transforms: []csvstatement.FieldTransform{
+				{Op: csvstatement.TransformSqueeze},
+			}},
+			{Name: "Note", Kind: csvstatement.FieldNotes, Pos: 4, Transforms: []csvstatement.FieldTransform{
+				{Op: csvstatement.TransformTrim},
+			}},
+		},
+		Fields: []csvstatement.ComposedField{
+			{
+				Kind:     csvstatement.FieldPayee,
+				Template: "{{.Text}}",
+				Transforms: []csvstatement.FieldTransform{
+					{Op: csvstatement.TransformExtract, Pattern: `^VISA \d+ (.*?) \d+$`},
+					{Op: csvstatement.TransformTitle},
+				},
+			},
+		},
+	}
+	csvData := "Date,Amount,Text,Note\n" +
+		"2025-01-01,NOK -12.50,  VISA 1234   KIWI MAJORSTUEN  5678 ,  split  with Kari \n" +
+		"2025-01-02,NOK 100.00,Lønn,\n"
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(got.Transactions))
+	}
+
+	first := got.Transactions[0]
+	if first.Amount != -1250 || first.Description != "VISA 1234 KIWI MAJORSTUEN 5678" || first.CounterpartName != "Kiwi Majorstuen" ||
+		first.Notes != "split  with Kari" {
+		t.Errorf("unexpected first transaction: %+v", first)
+	}
+	// The extract pattern does not match, so the value is kept.
+	if second := got.Transactions[1]; second.CounterpartName != "Lønn" {
+		t.Errorf("got payee %q of the second transaction", second.CounterpartName)
+	}
+}
+
+func TestFormat_Validate_Transforms(t *testing.T) {
+	format := csvstatement.Format{
+		DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2, Transforms: []csvstatement.FieldTransform{{Op: "shout"}}},
+		},
+		Fields: []csvstatement.ComposedField{
+			{Kind: csvstatement.FieldAmount, Template: "{{.Amount}}"},
+			{Kind: csvstatement.FieldMemo, Template: "{{.Amount"},
+		},
+	}
+	problems := format.Validate()
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", problems)
+	}
+}
@@ -1,17 +1,13 @@
 package csvstatement
 
-import "io"
+import (
+	"fincli/internal/statement"
+	"io"
+)
 
-func Convert(source io.Reader, target io.Writer, sourceFormat, targetFormat Format) error {
-	p := NewParser(sourceFormat)
-	stmt, err := p.Parse(source)
-	if err != nil {
-		return err
-	}
-
-	err = WriteStatement(target, stmt, targetFormat)
-	if err != nil {
-		return err
-	}
-	return nil
+// Convert reads the statement in source and writes it to target in another
+// format, applying transforms in order. Transactions are streamed from
+// source to target, so the statement is never held in memory as a whole.
+func Convert(source io.Reader, target io.Writer, sourceFormat, targetFormat Format, transforms ...statement.Transform) error {
+	return statement.Convert(source, target, NewParser(sourceFormat), NewWriter(targetFormat), transforms...)
 }
@@ -0,0 +1,112 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"testing"
+)
+
+// statementReader generates a bulder statement with n transactions without
+// holding it in memory. Close the reader to stop the generator early.
+func statementReader(n int) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		fmt.Fprintln(pw, "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;"+
+			"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori")
+		for i := range n {
+			fmt.Fprintf(pw, "2025-01-01;;%d,00;;;;;;Txn %d;;;\n", i%1000, i)
+		}
+		pw.Close()
+	}()
+	return pr
+}
+
+func TestParser_All_StopsEarly(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, err := registry.Get("bulder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := statementReader(1_000_000)
+	defer source.Close()
+
+	count := 0
+	for txn, err := range csvstatement.NewParser(format).All(source) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if txn.Description != fmt.Sprintf("Txn %d", count) {
+			t.Errorf("unexpected transaction %d: %+v", count, txn)
+		}
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected 3 transactions, got %d", count)
+	}
+}
+
+func TestConvert_Transforms(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulder, _ := registry.Get("bulder")
+	ynab, _ := registry.Get("ynab")
+
+	upper := func(txns iter.Seq2[domain.Transaction, error]) iter.Seq2[domain.Transaction, error] {
+		return func(yield func(domain.Transaction, error) bool) {
+			for txn, err := range txns {
+				txn.Description = strings.ToUpper(txn.Description)
+				if !yield(txn, err) {
+					return
+				}
+			}
+		}
+	}
+
+	var out strings.Builder
+	err = csvstatement.Convert(statementReader(2), &out, bulder, ynab, upper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Date,Payee,Memo,Inflow,Outflow\n" +
+		"2025-01-01,,TXN 0,0.00,0.00\n" +
+		"2025-01-01,,TXN 1,0.00,1.00\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConvert_PropagatesParseError(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulder, _ := registry.Get("bulder")
+	ynab, _ := registry.Get("ynab")
+
+	csvData := "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;" +
+		"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori\n" +
+		"2025-01-01;;12,34;;;;;;Groceries;;;\n" +
+		"not a date;;12,34;;;;;;Groceries;;;\n"
+
+	var out strings.Builder
+	err = csvstatement.Convert(strings.NewReader(csvData), &out, bulder, ynab)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.HasPrefix(out.String(), "Date,Payee,Memo,Inflow,Outflow\n2025-01-01,,Groceries,0.00,12.34\n") {
+		t.Errorf("expected transactions before the error to be written, got %q", out.String())
+	}
+}
@@ -0,0 +1,109 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
+	"fincli/internal/statement"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_CurrencyAndDates(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ';', HasHeader: true, DateFormat: "02.01.2006",
+		DecimalSeparator: ',', Currency: "NOK",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Bokført", Kind: csvstatement.FieldBookingDate, Pos: 1},
+			{Name: "Rentedato", Kind: csvstatement.FieldValueDate, Pos: 2},
+			{Name: "Beløp", Kind: csvstatement.FieldAmount, Pos: 3},
+			{Name: "Valuta", Kind: csvstatement.FieldCurrency, Pos: 4},
+		},
+	}
+	csvData := "Bokført;Rentedato;Beløp;Valuta\n" +
+		"02.01.2025;03.01.2025;-12,34;\n" +
+		"04.01.2025;;1500;jpy\n" +
+		"05.01.2025;;1,5;EUR\n" +
+		"06.01.2025;;2,5; eur \n" +
+		"07.01.2025;;-1; \n"
+
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	date := func(day int) time.Time { return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC) }
+	want := []domain.Transaction{
+		{Date: date(2), BookingDate: date(2), ValueDate: date(3), Amount: -1234, Currency: "NOK"},
+		{Date: date(4), BookingDate: date(4), Amount: 1500, Currency: "JPY"},
+		{Date: date(5), BookingDate: date(5), Amount: 150, Currency: "EUR"},
+		{Date: date(6), BookingDate: date(6), Amount: 250, Currency: "EUR"},
+		{Date: date(7), BookingDate: date(7), Amount: -100, Currency: "NOK"},
+	}
+	if len(got.Transactions) != len(want) {
+		t.Fatalf("expected %d transactions, got %d", len(want), len(got.Transactions))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got.Transactions[i], want[i]) {
+			t.Errorf("transaction %d:\ngot:\t%+v\nwant:\t%+v", i, got.Transactions[i], want[i])
+		}
+	}
+}
+
+func TestParser_InvalidCurrency(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ',', DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Currency", Kind: csvstatement.FieldCurrency, Pos: 2},
+		},
+	}
+
+	_, err := csvstatement.NewParser(format).Parse(strings.NewReader("2025-01-01,KRONER\n"))
+	var recErr *statement.RecordError
+	if !errors.As(err, &recErr) || recErr.Column != "Currency" {
+		t.Fatalf("expected RecordError for the currency column, got %v", err)
+	}
+
+	format.Currency = "ABC"
+	if format.CanRead() {
+		t.Errorf("expected format with unknown default currency to be unreadable")
+	}
+}
+
+func TestWriteStatement_CurrencyAndDates(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly, DecimalSeparator: '.',
+		Currency: "NOK",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Booked", Kind: csvstatement.FieldBookingDate, Pos: 2},
+			{Name: "Value", Kind: csvstatement.FieldValueDate, Pos: 3},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 4},
+			{Name: "Currency", Kind: csvstatement.FieldCurrency, Pos: 5},
+		},
+	}
+	statement := csvstatement.ParsedStatement{
+		Transactions: []domain.Transaction{
+			{
+				Date:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				BookingDate: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+				Amount:      -1500, Currency: "JPY",
+			},
+			{Date: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), Amount: 1234},
+		},
+	}
+
+	var out strings.Builder
+	if err := csvstatement.WriteStatement(&out, statement, format); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Date,Booked,Value,Amount,Currency\n" +
+		"2025-01-01,2025-01-02,,-1500,JPY\n" +
+		"2025-01-03,,,12.34,NOK\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
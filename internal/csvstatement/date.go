@@ -0,0 +1,96 @@
+package csvstatement
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Time zones of formats are found without the zoneinfo of the system,
+	// which is missing on some platforms.
+	_ "time/tzdata"
+)
+
+// DatePresets are date formats that can be used in place of Go layouts, such
+// as "dd.mm.yyyy" for "02.01.2006". Presets are matched ignoring case.
+var DatePresets = map[string]string{
+	"yyyy-mm-dd":          time.DateOnly,
+	"yyyy-mm-dd hh:mm:ss": time.DateTime,
+	"yyyymmdd":            "20060102",
+	"yyyy/mm/dd":          "2006/01/02",
+	"dd.mm.yyyy":          "02.01.2006",
+	"dd.mm.yyyy hh:mm":    "02.01.2006 15:04",
+	"dd.mm.yyyy hh:mm:ss": "02.01.2006 15:04:05",
+	"dd.mm.yy":            "02.01.06",
+	"d.m.yyyy":            "2.1.2006",
+	"dd/mm/yyyy":          "02/01/2006",
+	"dd-mm-yyyy":          "02-01-2006",
+	"mm/dd/yyyy":          "01/02/2006",
+	"mm/dd/yy":            "01/02/06",
+	"m/d/yyyy":            "1/2/2006",
+	"rfc3339":             time.RFC3339,
+}
+
+// DateLayout returns the Go layout of a date format, which is either one of
+// the [DatePresets] or a Go layout.
+func DateLayout(format string) string {
+	if layout, ok := DatePresets[strings.ToLower(strings.TrimSpace(format))]; ok {
+		return layout
+	}
+	return format
+}
+
+// dateLayouts returns the Go layouts that dates are parsed with, in the order
+// they are tried: DateFormat and then DateFormats.
+func (f Format) dateLayouts() []string {
+	var layouts []string
+	for _, format := range append([]string{f.DateFormat}, f.DateFormats...) {
+		if format != "" {
+			layouts = append(layouts, DateLayout(format))
+		}
+	}
+	return layouts
+}
+
+// location returns the time zone of the dates in the format. Dates are in UTC
+// if the format has no time zone.
+func (f Format) location() (*time.Location, error) {
+	if f.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(f.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone '%s'", f.TimeZone)
+	}
+	return loc, nil
+}
+
+// parseDate parses value with the first of layouts that accepts it. Dates
+// without a time zone offset are in loc.
+func parseDate(layouts []string, value string, loc *time.Location) (time.Time, error) {
+	var firstErr error
+	for _, layout := range layouts {
+		date, err := time.ParseInLocation(layout, strings.TrimSpace(value), loc)
+		if err == nil {
+			return date, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if len(layouts) > 1 {
+		return time.Time{}, fmt.Errorf("%q does not match any of the date formats %q", value, layouts)
+	}
+	if firstErr == nil {
+		return time.Time{}, fmt.Errorf("format has no date format")
+	}
+	return time.Time{}, firstErr
+}
+
+// formatTime formats date with the DateFormat of the format, in the time
+// zone of the format.
+func (f Format) formatTime(date time.Time) string {
+	if loc, err := f.location(); err == nil && f.TimeZone != "" {
+		date = date.In(loc)
+	}
+	return date.Format(DateLayout(f.DateFormat))
+}
@@ -0,0 +1,115 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"fincli/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_DateFormats(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := csvstatement.Format{
+		Delimiter:        ';',
+		HasHeader:        true,
+		DateFormat:       "dd.mm.yyyy",
+		DateFormats:      []string{"yyyy-mm-dd", "dd.mm.yyyy hh:mm"},
+		TimeZone:         "Europe/Oslo",
+		DecimalSeparator: ',',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Beløp", Kind: csvstatement.FieldAmount, Pos: 2},
+		},
+	}
+	data := "Dato;Beløp\n" +
+		"02.01.2025;1,00\n" +
+		"2025-01-03;2,00\n" +
+		"04.01.2025 13:45;3,00\n" +
+		"2025-01-05T10:00:00Z;4,00\n"
+
+	var (
+		dates  []time.Time
+		failed []string
+	)
+	for txn, err := range csvstatement.NewParser(format).All(strings.NewReader(data)) {
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		dates = append(dates, txn.Date)
+	}
+
+	want := []time.Time{
+		time.Date(2025, time.January, 2, 0, 0, 0, 0, oslo),
+		time.Date(2025, time.January, 3, 0, 0, 0, 0, oslo),
+		time.Date(2025, time.January, 4, 13, 45, 0, 0, oslo),
+	}
+	if len(dates) != len(want) {
+		t.Fatalf("got dates %v, want %v", dates, want)
+	}
+	for i := range want {
+		if !dates[i].Equal(want[i]) || dates[i].Location().String() != "Europe/Oslo" {
+			t.Errorf("date %d: got %v, want %v", i, dates[i], want[i])
+		}
+	}
+	if len(failed) != 1 || !strings.Contains(failed[0], "does not match any of the date formats") {
+		t.Errorf("expected one date error, got %q", failed)
+	}
+}
+
+func TestWriteStatement_TimeZone(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:        ',',
+		DateFormat:       "yyyy-mm-dd hh:mm:ss",
+		TimeZone:         "Europe/Oslo",
+		DecimalSeparator: '.',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+		},
+	}
+	statement := csvstatement.ParsedStatement{Transactions: []domain.Transaction{
+		{Date: time.Date(2025, time.July, 1, 22, 30, 0, 0, time.UTC), Amount: 100},
+	}}
+
+	var buf strings.Builder
+	if err := csvstatement.WriteStatement(&buf, statement, format); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "2025-07-02 00:30:00,1.00\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFormat_Validate_TimeZone(t *testing.T) {
+	format := csvstatement.Format{
+		DateFormat: time.DateOnly,
+		TimeZone:   "Mars/Olympus_Mons",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 2},
+		},
+	}
+	problems := format.Validate()
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, "unknown time zone") {
+		t.Errorf("got problems %v", problems)
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := map[string]string{
+		"dd.mm.yyyy": "02.01.2006",
+		"MM/DD/YYYY": "01/02/2006",
+		"2006-01-02": "2006-01-02",
+	}
+	for format, want := range tests {
+		if got := csvstatement.DateLayout(format); got != want {
+			t.Errorf("DateLayout(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package csvstatement
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"fincli/internal/charset"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// detectMinScore is the lowest score a format needs to be considered a match
+// by [FormatRegistry.Detect].
+const detectMinScore = 0.5
+
+// Candidate is a format scored against a sample by [FormatRegistry.Detect].
+type Candidate struct {
+	Format Format
+	// Score is the share of checks on the sample that the format passed,
+	// from 0 to 1.
+	Score float64
+}
+
+// DetectError is returned by [FormatRegistry.Detect] when no format, or more
+// than one format, matches the sample equally well.
+type DetectError struct {
+	// Candidates are all readable formats, ranked by score.
+	Candidates []Candidate
+	Ambiguous  bool
+}
+
+func (e *DetectError) Error() string {
+	var b strings.Builder
+	if e.Ambiguous {
+		b.WriteString("could not detect format: several formats match equally well")
+	} else {
+		b.WriteString("could not detect format: no format matches")
+	}
+	b.WriteString("\ncandidates:")
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %-16s %3.0f%%", c.Format.Id, c.Score*100)
+	}
+	return b.String()
+}
+
+// Detect scores every readable format in the registry against sample, which
+// should hold the first lines of a statement, and returns the best match.
+//
+// A format is scored on whether its delimiter splits the sample into enough
+// fields, whether the header names match the column names, whether the dates
+// parse with its date format, and whether amounts use its decimal separator.
+// If no format scores high enough, or the best score is shared by several
+// formats, a [*DetectError] with the ranked candidates is returned.
+func (r FormatRegistry) Detect(sample []byte) (Format, error) {
+	var candidates []Candidate
+	for _, format := range r {
+		if !format.CanRead() {
+			continue
+		}
+		candidates = append(candidates, Candidate{Format: format, Score: scoreSample(format, sample)})
+	}
+	slices.SortFunc(candidates, func(a, b Candidate) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Format.Id, b.Format.Id)
+	})
+
+	if len(candidates) == 0 || candidates[0].Score < detectMinScore {
+		return Format{}, &DetectError{Candidates: candidates}
+	}
+	if len(candidates) > 1 && candidates[1].Score == candidates[0].Score {
+		return Format{}, &DetectError{Candidates: candidates, Ambiguous: true}
+	}
+	return candidates[0].Format, nil
+}
+
+// scoreSample returns the share of checks on sample that format passes.
+func scoreSample(format Format, sample []byte) float64 {
+	decoded, err := charset.NewReader(bytes.NewReader(sample), format.Encoding)
+	if err != nil {
+		return 0
+	}
+	// The sample may end in the middle of a character, so what could be
+	// decoded is used.
+	text, _ := io.ReadAll(decoded)
+	_, body, err := format.splitStatement(bytes.NewReader(text))
+	if err != nil {
+		return 0
+	}
+
+	reader := csv.NewReader(body)
+	if format.Delimiter != 0 {
+		reader.Comma = format.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil && len(records) == 0 {
+		return 0
+	}
+
+	var passed, total int
+	check := func(ok bool) {
+		total++
+		if ok {
+			passed++
+		}
+	}
+
+	if format.HasHeader && len(records) > 0 {
+		header := records[0]
+		records = records[1:]
+		if format.MatchHeaders {
+			resolved, err := format.ResolveHeader(header)
+			if err != nil {
+				return 0
+			}
+			format = resolved
+		}
+		for _, col := range format.ColumnMappings {
+			if col.Pos <= 0 {
+				continue
+			}
+			check(col.Pos <= len(header) && (format.MatchHeaders ||
+				strings.EqualFold(strings.TrimSpace(header[col.Pos-1]), strings.TrimSpace(col.Name))))
+		}
+	}
+
+	maxPos := 0
+	for _, col := range format.ColumnMappings {
+		maxPos = max(maxPos, col.Pos)
+	}
+
+	for _, record := range records {
+		if len(record) < maxPos {
+			// The delimiter does not split the record into enough fields, so
+			// none of the columns can be checked.
+			total += len(format.ColumnMappings)
+			continue
+		}
+		for _, col := range format.ColumnMappings {
+			if col.Pos <= 0 {
+				continue
+			}
+			value := strings.TrimSpace(record[col.Pos-1])
+			if value == "" {
+				continue
+			}
+			switch col.Kind {
+			case FieldDate, FieldBookingDate, FieldValueDate:
+				_, err := parseDate(format.dateLayouts(), value, time.UTC)
+				check(err == nil)
+			case FieldInflow, FieldOutflow, FieldAmount:
+				check(looksLikeAmount(value, format.DecimalSeparator))
+			}
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(passed) / float64(total)
+}
+
+// looksLikeAmount reports whether value is a number that uses sep as decimal
+// separator. Values without any separator are accepted for any sep.
+func looksLikeAmount(value string, sep rune) bool {
+	lastSep := rune(0)
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9', r == ' ', r == '+', r == '-', r == '(', r == ')':
+		case r == '.' || r == ',':
+			lastSep = r
+		default:
+			return false
+		}
+	}
+	return lastSep == 0 || sep == 0 || lastSep == sep
+}
@@ -0,0 +1,89 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"testing"
+)
+
+func TestFormatRegistry_Detect(t *testing.T) {
+	tests := []struct {
+		name          string
+		sample        string
+		wantId        string
+		wantAmbiguous bool
+		wantErr       bool
+	}{
+		{
+			name: "bulder",
+			sample: "Dato;Inn på konto;Ut fra konto;Til konto;Til kontonummer;" +
+				"Fra konto;Fra kontonummer;Type;Tekst;KID;Hovedkategori;Underkategori\n" +
+				"2025-01-01;;12,34;;;;;;Groceries;;;\n" +
+				"2025-01-02;500,00;;;;;;;Deposit;;;\n",
+			wantId: "bulder",
+		},
+		{
+			name: "ynab",
+			sample: "Date,Payee,Memo,Inflow,Outflow\n" +
+				"2025-01-01,Store,Groceries,0.00,12.34\n",
+			wantId: "ynab",
+		},
+		{
+			name:    "unknown",
+			sample:  "foo|bar\nbaz|qux\n",
+			wantErr: true,
+		},
+	}
+
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Detect([]byte(tt.sample))
+			if tt.wantErr {
+				var detectErr *csvstatement.DetectError
+				if !errors.As(err, &detectErr) {
+					t.Fatalf("expected DetectError, got %v", err)
+				}
+				if len(detectErr.Candidates) != len(*registry) {
+					t.Errorf("expected %d candidates, got %d", len(*registry), len(detectErr.Candidates))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Id != tt.wantId {
+				t.Errorf("detected %q, want %q", got.Id, tt.wantId)
+			}
+		})
+	}
+}
+
+func TestFormatRegistry_Detect_Ambiguous(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ynab, err := registry.Get("ynab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ynab.Id = "ynab-copy"
+	(*registry)[ynab.Id] = ynab
+
+	_, err = registry.Detect([]byte("Date,Payee,Memo,Inflow,Outflow\n2025-01-01,Store,Groceries,0.00,12.34\n"))
+	var detectErr *csvstatement.DetectError
+	if !errors.As(err, &detectErr) {
+		t.Fatalf("expected DetectError, got %v", err)
+	}
+	if !detectErr.Ambiguous {
+		t.Errorf("expected ambiguous match")
+	}
+	if detectErr.Candidates[0].Format.Id != "ynab" || detectErr.Candidates[1].Format.Id != "ynab-copy" {
+		t.Errorf("unexpected ranking: %v", detectErr.Error())
+	}
+}
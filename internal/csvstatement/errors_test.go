@@ -0,0 +1,87 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"fincli/internal/statement"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_All_RecordErrors(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:  ',',
+		HasHeader:  true,
+		DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Inflow", Kind: csvstatement.FieldInflow, Pos: 2},
+		},
+	}
+	csvData := "Date,Inflow\n" +
+		"2025-01-01,1.00\n" +
+		"01.02.2025,2.00\n" +
+		"2025-01-03,abc\n" +
+		"2025-01-04,4.00,extra\n" +
+		"2025-01-05,5.00\n"
+
+	parser := csvstatement.NewParser(format)
+	parser.SetFileName("bank.csv")
+
+	var skipped []*statement.RecordError
+	var amounts []int
+	for txn, err := range statement.SkipRecordErrors(&skipped)(parser.All(strings.NewReader(csvData))) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		amounts = append(amounts, txn.Amount)
+	}
+
+	if len(amounts) != 2 || amounts[0] != 100 || amounts[1] != 500 {
+		t.Errorf("unexpected amounts %v", amounts)
+	}
+
+	want := []struct {
+		line   int
+		column string
+		value  string
+	}{
+		{3, "Date", "01.02.2025"},
+		{4, "Inflow", "abc"},
+		{5, "", ""},
+	}
+	if len(skipped) != len(want) {
+		t.Fatalf("expected %d skipped rows, got %d: %v", len(want), len(skipped), skipped)
+	}
+	for i, w := range want {
+		got := skipped[i]
+		if got.File != "bank.csv" || got.Line != w.line || got.Column != w.column || got.Value != w.value {
+			t.Errorf("skipped row %d: got %+v, want line %d, column %q, value %q", i, got, w.line, w.column, w.value)
+		}
+	}
+
+	wantMsg := "bank.csv: line 3, column 'Date', value '01.02.2025': could not parse date: "
+	if !strings.HasPrefix(skipped[0].Error(), wantMsg) {
+		t.Errorf("unexpected message %q", skipped[0].Error())
+	}
+}
+
+func TestParser_Parse_StopsAtRecordError(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:  ',',
+		DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+		},
+	}
+
+	_, err := csvstatement.NewParser(format).Parse(strings.NewReader("2025-01-01\nbad\n"))
+	var recErr *statement.RecordError
+	if !errors.As(err, &recErr) {
+		t.Fatalf("expected RecordError, got %v", err)
+	}
+	if recErr.Line != 2 {
+		t.Errorf("expected line 2, got %d", recErr.Line)
+	}
+}
@@ -1,20 +1,65 @@
 package csvstatement
 
 import (
+	"fincli/internal/money"
 	"fmt"
-	"time"
+	"io/fs"
 )
 
 // Format describes the strucutre of a CSV statement file.
 //
 // For convenience use [NewFormat] which sets sensible defaults.
 type Format struct {
-	Id               string
-	Delimiter        rune
-	HasHeader        bool
-	DateFormat       string
+	Id        string
+	Delimiter rune
+	HasHeader bool
+	// DateFormat is the Go layout of dates, such as "02.01.2006", or one of
+	// the [DatePresets], such as "dd.mm.yyyy". Dates are written with it.
+	DateFormat string
+	// DateFormats are other date formats that dates are parsed with, in
+	// order, if DateFormat does not match.
+	DateFormats []string
+	// TimeZone is the IANA name of the time zone of dates, such as
+	// "Europe/Oslo". Empty means UTC.
+	TimeZone         string
 	DecimalSeparator rune
-	ColumnMappings   []TransactionColumn
+	// ThousandsSeparator groups the digits of amounts, such as '.' in
+	// "1.234,56". Spaces are always accepted. Zero means no grouping.
+	ThousandsSeparator rune
+	// Currency is the ISO 4217 code of the amounts in the statement, used
+	// for transactions without a currency column. The currency decides the
+	// number of decimals of amounts, see [money.Exponent].
+	Currency string
+	// Encoding is the IANA name of the character encoding of the statement,
+	// such as "windows-1252" or "UTF-16LE". Empty means UTF-8. A byte order
+	// mark in the statement takes precedence when reading.
+	Encoding       string
+	ColumnMappings []TransactionColumn
+	// Fields are transaction fields composed from several columns. They
+	// are set when reading, after the columns.
+	Fields []ComposedField
+
+	// MatchHeaders makes the parser find the position of each column by its
+	// name in the header row, rather than using the configured positions.
+	// It requires HasHeader.
+	MatchHeaders bool
+
+	// SkipLines is the number of lines before the header row, or before the
+	// first record if the format has no header, such as lines with the
+	// account number and the period of the statement.
+	SkipLines int
+	// HeaderRowPattern is a regular expression that matches the header row,
+	// or the first record if the format has no header. The lines before it
+	// are skipped, after any SkipLines.
+	HeaderRowPattern string
+	// FooterPattern is a regular expression that matches the first line after
+	// the records, such as a row with the balance or the totals. The line and
+	// the rest of the statement are ignored.
+	FooterPattern string
+
+	// Source tells where the format was loaded from, such as the path of a
+	// layout file. It is empty for formats created in code.
+	Source string
 }
 
 // NewFormat returns a Format with HasHeader set to true by default.
@@ -26,6 +71,67 @@ type TransactionColumn struct {
 	Name string
 	Kind FieldKind
 	Pos  int // Column position, starts at 1 (one). A 0 or negative value means not present.
+
+	// Aliases are other header names the column is known by. They are used
+	// together with Name when the format has MatchHeaders set.
+	Aliases []string
+	// HeaderPattern is a regular expression that matches the header name of
+	// the column, used when neither Name nor any alias matches.
+	HeaderPattern string
+	// Optional columns may be missing from the header.
+	Optional bool
+
+	// DebitValues and CreditValues are the values of a direction column that
+	// mark a transaction as money going out or coming in. They are compared
+	// ignoring case, and default to [DefaultDebitValues] and
+	// [DefaultCreditValues].
+	DebitValues  []string
+	CreditValues []string
+
+	// Transforms are applied to the value of the column when reading, in
+	// order, before it is parsed.
+	Transforms []FieldTransform
+}
+
+// Default values of a direction column.
+var (
+	DefaultDebitValues  = []string{"D", "DR", "Debit"}
+	DefaultCreditValues = []string{"C", "CR", "Credit"}
+)
+
+// debitValues returns the values that mark a debit in a direction column.
+func (c TransactionColumn) debitValues() []string {
+	if len(c.DebitValues) > 0 {
+		return c.DebitValues
+	}
+	return DefaultDebitValues
+}
+
+// creditValues returns the values that mark a credit in a direction column.
+func (c TransactionColumn) creditValues() []string {
+	if len(c.CreditValues) > 0 {
+		return c.CreditValues
+	}
+	return DefaultCreditValues
+}
+
+// hasKind reports whether the format has a column of the given kind.
+func (f Format) hasKind(kind FieldKind) bool {
+	for _, col := range f.ColumnMappings {
+		if col.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// moneyParser returns a parser for amounts in currency in the format.
+func (f Format) moneyParser(currency string) money.Parser {
+	return money.Parser{
+		DecimalSeparator:   f.DecimalSeparator,
+		ThousandsSeparator: f.ThousandsSeparator,
+		Exponent:           money.Exponent(currency),
+	}
 }
 
 // FieldKind describes the kind of data in a column
@@ -37,43 +143,73 @@ const (
 	FieldMemo    FieldKind = "memo"
 	FieldInflow  FieldKind = "inflow"
 	FieldOutflow FieldKind = "outflow"
+
+	// FieldAmount is a signed amount, where negative amounts are money
+	// going out. Negative amounts may have a leading or trailing minus, or
+	// be enclosed in parentheses.
+	FieldAmount FieldKind = "amount"
+	// FieldDirection tells whether the amount of the transaction is a debit
+	// or a credit, see [TransactionColumn.DebitValues]. It overrides the sign
+	// of the amount.
+	FieldDirection FieldKind = "direction"
+
+	FieldBookingDate FieldKind = "booking_date"
+	FieldValueDate   FieldKind = "value_date"
+	// FieldCurrency is the ISO 4217 code of the currency of the amount. It
+	// overrides the currency of the format.
+	FieldCurrency FieldKind = "currency"
+
+	// FieldCategory is the category of the transaction. The values of
+	// several category columns are joined with ':', in order, such as a main
+	// category column and a subcategory column.
+	FieldCategory FieldKind = "category"
+	// FieldSubcategory is the part of the category that follows the
+	// category columns. When writing, the category columns get the first
+	// level of the category and the subcategory columns the rest.
+	FieldSubcategory FieldKind = "subcategory"
+	// FieldTags holds the tags of the transaction, separated by commas.
+	FieldTags  FieldKind = "tags"
+	FieldNotes FieldKind = "notes"
 )
 
+// FormatRegistry holds the known formats by their Id.
 type FormatRegistry map[string]Format
 
 type Factory struct {
 	InitRegistry *FormatRegistry
+
+	// LayoutDirs are directories with YAML layouts that are loaded on top of
+	// the built-in layouts, in order. Later layouts override earlier ones
+	// with the same Id.
+	LayoutDirs []string
 }
 
-func NewRegistry(factory *Factory) *FormatRegistry {
+// NewRegistry returns a registry with the built-in layouts and the layouts
+// found in factory.LayoutDirs.
+//
+// If factory.InitRegistry is set, it is returned as is.
+func NewRegistry(factory *Factory) (*FormatRegistry, error) {
 	if factory != nil && factory.InitRegistry != nil {
-		return factory.InitRegistry
+		return factory.InitRegistry, nil
 	}
-	return &defaultRegistry
-}
 
-var defaultRegistry = FormatRegistry{
-	"bulder": {
-		Id: "bulder", Delimiter: ';', HasHeader: true, DateFormat: time.DateOnly,
-		DecimalSeparator: ',',
-		ColumnMappings: []TransactionColumn{
-			{Name: "Dato", Kind: FieldDate, Pos: 1},
-			{Name: "Tekst", Kind: FieldMemo, Pos: 9},
-			{Name: "Inn på konto", Kind: FieldInflow, Pos: 2},
-			{Name: "Ut fra konto", Kind: FieldOutflow, Pos: 3},
-		},
-	},
-	"ynab": {
-		Id: "ynab", Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly,
-		DecimalSeparator: '.',
-		ColumnMappings: []TransactionColumn{
-			{Name: "Date", Kind: FieldDate, Pos: 1},
-			{Name: "Payee", Kind: FieldPayee, Pos: 2},
-			{Name: "Memo", Kind: FieldMemo, Pos: 3},
-			{Name: "Inflow", Kind: FieldInflow, Pos: 4},
-			{Name: "Outflow", Kind: FieldOutflow, Pos: 5},
-		},
-	},
+	registry := FormatRegistry{}
+	builtins, err := fs.Sub(builtinLayouts, "layouts")
+	if err != nil {
+		return nil, err
+	}
+	if err := loadLayouts(registry, builtins, "builtin:"); err != nil {
+		return nil, fmt.Errorf("could not load built-in layouts: %w", err)
+	}
+
+	if factory != nil {
+		for _, dir := range factory.LayoutDirs {
+			if err := registry.LoadLayoutDir(dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &registry, nil
 }
 
 func (r FormatRegistry) Get(name string) (Format, error) {
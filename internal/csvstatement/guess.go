@@ -0,0 +1,172 @@
+package csvstatement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateLayouts are the date formats [GuessFormat] tries, in order of
+// preference. Day-first layouts come before month-first layouts.
+var DateLayouts = []string{
+	time.DateOnly,
+	"02.01.2006",
+	"02/01/2006",
+	"01/02/2006",
+	"2006/01/02",
+	"02-01-2006",
+	"2.1.2006",
+	"20060102",
+}
+
+// Delimiters are the field delimiters [GuessFormat] tries.
+var Delimiters = []rune{',', ';', '\t', '|'}
+
+// GuessFormat guesses the delimiter, header, date format and decimal
+// separator of the CSV statement in sample, which should hold the first
+// lines of the file.
+//
+// The column mappings of the returned format only contain the date column, if
+// one was found. The remaining columns are left for the caller to map.
+func GuessFormat(sample []byte) (Format, error) {
+	format := NewFormat()
+
+	var records [][]string
+	for _, delim := range Delimiters {
+		recs, err := readSample(sample, delim)
+		if err != nil || len(recs) == 0 {
+			continue
+		}
+		if records == nil || sampleWidth(recs) > sampleWidth(records) {
+			format.Delimiter = delim
+			records = recs
+		}
+	}
+	if records == nil {
+		return Format{}, fmt.Errorf("could not read sample as CSV")
+	}
+
+	format.HasHeader = !hasValues(records[0])
+	data := records
+	if format.HasHeader {
+		data = records[1:]
+	}
+
+	dateCol := -1
+	for _, layout := range DateLayouts {
+		for col := range records[0] {
+			if columnMatches(data, col, func(v string) bool {
+				_, err := time.Parse(layout, v)
+				return err == nil
+			}) {
+				format.DateFormat, dateCol = layout, col
+				break
+			}
+		}
+		if dateCol >= 0 {
+			break
+		}
+	}
+
+	var commas, dots int
+	for col := range records[0] {
+		if col == dateCol || !columnMatches(data, col, func(v string) bool { return looksLikeAmount(v, 0) }) {
+			continue
+		}
+		for _, rec := range data {
+			if col >= len(rec) {
+				continue
+			}
+			switch i := strings.LastIndexAny(rec[col], ",."); {
+			case i < 0:
+			case rec[col][i] == ',':
+				commas++
+			default:
+				dots++
+			}
+		}
+	}
+	format.DecimalSeparator = '.'
+	if commas > dots {
+		format.DecimalSeparator = ','
+	}
+
+	if dateCol >= 0 {
+		name := fmt.Sprintf("Column %d", dateCol+1)
+		if format.HasHeader {
+			name = records[0][dateCol]
+		}
+		format.ColumnMappings = []TransactionColumn{{Name: name, Kind: FieldDate, Pos: dateCol + 1}}
+	}
+	return format, nil
+}
+
+// readSample reads the records in sample. The last record is dropped if the
+// sample does not end with a newline, as it is most likely cut off.
+func readSample(sample []byte, delimiter rune) ([][]string, error) {
+	if i := bytes.LastIndexByte(sample, '\n'); i >= 0 && i < len(sample)-1 {
+		sample = sample[:i+1]
+	}
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// sampleWidth returns the number of fields in the records, or 0 if the
+// records do not all have the same number of fields.
+func sampleWidth(records [][]string) int {
+	width := len(records[0])
+	for _, rec := range records[1:] {
+		if len(rec) != width {
+			return 0
+		}
+	}
+	if width < 2 {
+		return 0
+	}
+	return width
+}
+
+// hasValues reports whether any field in record looks like a date
+// or an amount rather than a column name.
+func hasValues(record []string) bool {
+	for _, field := range record {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if looksLikeAmount(field, 0) {
+			return true
+		}
+		for _, layout := range DateLayouts {
+			if _, err := time.Parse(layout, field); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnMatches reports whether col has at least one non-empty value in
+// records, and all its non-empty values satisfy match.
+func columnMatches(records [][]string, col int, match func(string) bool) bool {
+	found := false
+	for _, rec := range records {
+		if col >= len(rec) {
+			return false
+		}
+		value := strings.TrimSpace(rec[col])
+		if value == "" {
+			continue
+		}
+		if !match(value) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,74 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"testing"
+)
+
+func TestGuessFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		sample      string
+		wantDelim   rune
+		wantHeader  bool
+		wantDate    string
+		wantDecSep  rune
+		wantDatePos int
+	}{
+		{
+			name: "semicolon with header",
+			sample: "Dato;Beløp;Tekst\n" +
+				"31.01.2025;-1 234,50;Groceries\n" +
+				"01.02.2025;500,00;Deposit\n",
+			wantDelim:   ';',
+			wantHeader:  true,
+			wantDate:    "02.01.2006",
+			wantDecSep:  ',',
+			wantDatePos: 1,
+		},
+		{
+			name: "comma without header",
+			sample: "Store,2025-01-31,12.34\n" +
+				"Bank,2025-02-01,500.00\n",
+			wantDelim:   ',',
+			wantHeader:  false,
+			wantDate:    "2006-01-02",
+			wantDecSep:  '.',
+			wantDatePos: 2,
+		},
+		{
+			name: "month first",
+			sample: "Date\tAmount\n" +
+				"01/31/2025\t1.00\n",
+			wantDelim:   '\t',
+			wantHeader:  true,
+			wantDate:    "01/02/2006",
+			wantDecSep:  '.',
+			wantDatePos: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := csvstatement.GuessFormat([]byte(tt.sample))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Delimiter != tt.wantDelim {
+				t.Errorf("delimiter: got %q, want %q", got.Delimiter, tt.wantDelim)
+			}
+			if got.HasHeader != tt.wantHeader {
+				t.Errorf("header: got %t, want %t", got.HasHeader, tt.wantHeader)
+			}
+			if got.DateFormat != tt.wantDate {
+				t.Errorf("date format: got %q, want %q", got.DateFormat, tt.wantDate)
+			}
+			if got.DecimalSeparator != tt.wantDecSep {
+				t.Errorf("decimal separator: got %q, want %q", got.DecimalSeparator, tt.wantDecSep)
+			}
+			if len(got.ColumnMappings) != 1 || got.ColumnMappings[0].Pos != tt.wantDatePos {
+				t.Errorf("expected date column at position %d, got %+v", tt.wantDatePos, got.ColumnMappings)
+			}
+		})
+	}
+}
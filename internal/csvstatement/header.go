@@ -0,0 +1,70 @@
+package csvstatement
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrMissingColumn is returned when a required column is not found in the
+// header row of a statement.
+var ErrMissingColumn = errors.New("required column is missing from header")
+
+// ResolveHeader returns a copy of the format where the position of each
+// column is the position of its name in header.
+//
+// A column matches a header name equal to its Name or one of its Aliases,
+// ignoring case and surrounding spaces, or a header name matched by its
+// HeaderPattern. Optional columns that are not found get position 0, and are
+// skipped when parsing. If a required column is not found, an error wrapping
+// [ErrMissingColumn] is returned.
+func (f Format) ResolveHeader(header []string) (Format, error) {
+	resolved := f
+	resolved.ColumnMappings = make([]TransactionColumn, len(f.ColumnMappings))
+
+	var missing []string
+	for i, col := range f.ColumnMappings {
+		pos, err := col.findInHeader(header)
+		if err != nil {
+			return Format{}, err
+		}
+		if pos == 0 && !col.Optional {
+			missing = append(missing, col.Name)
+		}
+		col.Pos = pos
+		resolved.ColumnMappings[i] = col
+	}
+
+	if len(missing) > 0 {
+		return Format{}, fmt.Errorf("%w: could not find %q in header %q", ErrMissingColumn, missing, header)
+	}
+	return resolved, nil
+}
+
+// findInHeader returns the 1-based position of the column in header, or 0 if
+// the column is not found.
+func (c TransactionColumn) findInHeader(header []string) (int, error) {
+	names := append([]string{c.Name}, c.Aliases...)
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i + 1, nil
+			}
+		}
+	}
+
+	if c.HeaderPattern == "" {
+		return 0, nil
+	}
+	pattern, err := regexp.Compile(c.HeaderPattern)
+	if err != nil {
+		return 0, fmt.Errorf("invalid header pattern for column '%s': %w", c.Name, err)
+	}
+	for i, h := range header {
+		if pattern.MatchString(strings.TrimSpace(h)) {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
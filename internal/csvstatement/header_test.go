@@ -0,0 +1,97 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_MatchHeaders(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:        ';',
+		HasHeader:        true,
+		MatchHeaders:     true,
+		DateFormat:       time.DateOnly,
+		DecimalSeparator: ',',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Inn", Aliases: []string{"Inn på konto"}, Kind: csvstatement.FieldInflow, Pos: 2},
+			{Name: "Ut", HeaderPattern: `(?i)^ut\b`, Kind: csvstatement.FieldOutflow, Pos: 3},
+			{Name: "Tekst", Kind: csvstatement.FieldMemo, Pos: 4},
+			{Name: "Mottaker", Kind: csvstatement.FieldPayee, Optional: true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		csv     string
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "columns in configured order",
+			csv:  "Dato;Inn på konto;Ut fra konto;Tekst\n2025-01-01;;12,34;Groceries\n",
+			want: []int{-1234},
+		},
+		{
+			name: "inserted and reordered columns",
+			csv:  "Type;Tekst;Ut fra konto;Dato;Inn på konto\nKort;Groceries;12,34;2025-01-01;\n;Deposit;;2025-01-02;500,00\n",
+			want: []int{-1234, 50000},
+		},
+		{
+			name:    "missing required column",
+			csv:     "Dato;Ut fra konto;Tekst\n2025-01-01;12,34;Groceries\n",
+			wantErr: csvstatement.ErrMissingColumn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := csvstatement.NewParser(format)
+			got, err := parser.Parse(strings.NewReader(tt.csv))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				if !strings.Contains(err.Error(), `"Inn"`) {
+					t.Errorf("expected error to name the missing column, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got.Transactions) != len(tt.want) {
+				t.Fatalf("expected %d transactions, got %d", len(tt.want), len(got.Transactions))
+			}
+			for i, amount := range tt.want {
+				if got.Transactions[i].Amount != amount {
+					t.Errorf("transaction %d: amount %d, want %d", i, got.Transactions[i].Amount, amount)
+				}
+				if got.Transactions[i].Date.IsZero() {
+					t.Errorf("transaction %d: date not parsed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_PositionalWithoutHeader(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:  ',',
+		DateFormat: time.DateOnly,
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 2},
+			{Name: "Amount", Kind: csvstatement.FieldInflow, Pos: 1},
+		},
+	}
+	got, err := csvstatement.NewParser(format).Parse(strings.NewReader("5.00,2025-01-01\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Transactions) != 1 || got.Transactions[0].Amount != 500 {
+		t.Errorf("unexpected transactions: %+v", got.Transactions)
+	}
+}
@@ -0,0 +1,219 @@
+package csvstatement
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed layouts/*.yaml
+var builtinLayouts embed.FS
+
+// layout is the YAML representation of a Format.
+//
+// Single-character settings such as the delimiter are written as strings in
+// YAML, and converted to runes when the layout is turned into a Format.
+type layout struct {
+	Id                 string         `yaml:"id"`
+	Delimiter          string         `yaml:"delimiter"`
+	HasHeader          *bool          `yaml:"header,omitempty"`
+	DateFormat         string         `yaml:"date_format"`
+	DateFormats        []string       `yaml:"date_formats,omitempty"`
+	TimeZone           string         `yaml:"time_zone,omitempty"`
+	DecimalSeparator   string         `yaml:"decimal_separator"`
+	ThousandsSeparator string         `yaml:"thousands_separator,omitempty"`
+	Currency           string         `yaml:"currency,omitempty"`
+	Encoding           string         `yaml:"encoding,omitempty"`
+	Columns            []layoutColumn `yaml:"columns"`
+	Fields             []layoutField  `yaml:"fields,omitempty"`
+	MatchHeaders       bool           `yaml:"match_headers,omitempty"`
+	SkipLines          int            `yaml:"skip_lines,omitempty"`
+	HeaderRowPattern   string         `yaml:"header_row_pattern,omitempty"`
+	FooterPattern      string         `yaml:"footer_pattern,omitempty"`
+}
+
+type layoutColumn struct {
+	Name          string    `yaml:"name"`
+	Kind          FieldKind `yaml:"kind"`
+	Pos           int       `yaml:"pos,omitempty"`
+	Aliases       []string  `yaml:"aliases,omitempty"`
+	HeaderPattern string    `yaml:"pattern,omitempty"`
+	Optional      bool      `yaml:"optional,omitempty"`
+	DebitValues   []string  `yaml:"debit,omitempty"`
+	CreditValues  []string  `yaml:"credit,omitempty"`
+
+	Transforms []FieldTransform `yaml:"transforms,omitempty"`
+}
+
+type layoutField struct {
+	Kind       FieldKind        `yaml:"kind"`
+	Template   string           `yaml:"template"`
+	Transforms []FieldTransform `yaml:"transforms,omitempty"`
+}
+
+// ParseLayout decodes a YAML layout into a Format.
+//
+// Settings that are left out of the layout get the defaults of [NewFormat].
+func ParseLayout(data []byte) (Format, error) {
+	var l layout
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return Format{}, fmt.Errorf("could not decode layout: %w", err)
+	}
+	return l.format()
+}
+
+// MarshalLayout encodes a Format as a YAML layout that can be read back with
+// [ParseLayout].
+func MarshalLayout(format Format) ([]byte, error) {
+	hasHeader := format.HasHeader
+	l := layout{
+		Id:           format.Id,
+		HasHeader:    &hasHeader,
+		DateFormat:   format.DateFormat,
+		DateFormats:  format.DateFormats,
+		TimeZone:     format.TimeZone,
+		Columns:      make([]layoutColumn, 0, len(format.ColumnMappings)),
+		MatchHeaders: format.MatchHeaders,
+	}
+	if format.Delimiter != 0 {
+		l.Delimiter = string(format.Delimiter)
+	}
+	if format.DecimalSeparator != 0 {
+		l.DecimalSeparator = string(format.DecimalSeparator)
+	}
+	if format.ThousandsSeparator != 0 {
+		l.ThousandsSeparator = string(format.ThousandsSeparator)
+	}
+	l.Currency = format.Currency
+	l.Encoding = format.Encoding
+	l.SkipLines = format.SkipLines
+	l.HeaderRowPattern = format.HeaderRowPattern
+	l.FooterPattern = format.FooterPattern
+	for _, col := range format.ColumnMappings {
+		l.Columns = append(l.Columns, layoutColumn(col))
+	}
+	for _, field := range format.Fields {
+		l.Fields = append(l.Fields, layoutField(field))
+	}
+	return yaml.Marshal(l)
+}
+
+func (l layout) format() (Format, error) {
+	format := NewFormat()
+	format.Id = l.Id
+	format.DateFormat = l.DateFormat
+	format.DateFormats = l.DateFormats
+	format.TimeZone = l.TimeZone
+	format.MatchHeaders = l.MatchHeaders
+	if l.HasHeader != nil {
+		format.HasHeader = *l.HasHeader
+	}
+
+	var err error
+	if format.Delimiter, err = singleRune("delimiter", l.Delimiter); err != nil {
+		return Format{}, err
+	}
+	if format.DecimalSeparator, err = singleRune("decimal_separator", l.DecimalSeparator); err != nil {
+		return Format{}, err
+	}
+	if format.ThousandsSeparator, err = singleRune("thousands_separator", l.ThousandsSeparator); err != nil {
+		return Format{}, err
+	}
+	format.Currency = l.Currency
+	format.Encoding = l.Encoding
+	format.SkipLines = l.SkipLines
+	format.HeaderRowPattern = l.HeaderRowPattern
+	format.FooterPattern = l.FooterPattern
+
+	format.ColumnMappings = make([]TransactionColumn, 0, len(l.Columns))
+	for _, col := range l.Columns {
+		format.ColumnMappings = append(format.ColumnMappings, TransactionColumn(col))
+	}
+	for _, field := range l.Fields {
+		format.Fields = append(format.Fields, ComposedField(field))
+	}
+	return format, nil
+}
+
+// singleRune returns the only rune in value, or 0 if value is empty.
+func singleRune(key, value string) (rune, error) {
+	if value == "" {
+		return 0, nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) {
+		return 0, fmt.Errorf("layout setting '%s' must be a single character, got '%s'", key, value)
+	}
+	return r, nil
+}
+
+// loadLayouts reads all YAML layouts in the root of fsys into registry. The
+// Source of each format is the file name prefixed with source.
+//
+// Layouts without an id are registered under their file name without the
+// extension. Layouts replace any format already registered with the same id.
+func loadLayouts(registry FormatRegistry, fsys fs.FS, source string) error {
+	matches, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return err
+	}
+	for _, name := range matches {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("could not read layout %s: %w", name, err)
+		}
+		format, err := parseLayoutFile(data, name, source+name)
+		if err != nil {
+			return err
+		}
+		registry[format.Id] = format
+	}
+	return nil
+}
+
+// ReadLayoutFile reads a single YAML layout file.
+func ReadLayoutFile(path string) (Format, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Format{}, fmt.Errorf("could not read layout: %w", err)
+	}
+	return parseLayoutFile(data, filepath.Base(path), path)
+}
+
+// parseLayoutFile decodes the layout in the file name, and records source as
+// the Source of the format. Layouts without an id get the file name without
+// the extension as Id.
+func parseLayoutFile(data []byte, name, source string) (Format, error) {
+	format, err := ParseLayout(data)
+	if err != nil {
+		return Format{}, fmt.Errorf("layout %s: %w", source, err)
+	}
+	if format.Id == "" {
+		format.Id = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	format.Source = source
+	return format, nil
+}
+
+// LoadLayoutDir reads all YAML layouts in dir into the registry, replacing
+// formats with the same id. A missing directory is not an error.
+func (r FormatRegistry) LoadLayoutDir(dir string) error {
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read layout directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("layout directory %s is not a directory", dir)
+	}
+	return loadLayouts(r, os.DirFS(dir), dir+string(filepath.Separator))
+}
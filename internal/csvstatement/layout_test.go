@@ -0,0 +1,123 @@
+package csvstatement_test
+
+import (
+	"fincli/internal/csvstatement"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLayout(t *testing.T) {
+	data := []byte(`
+id: mybank
+delimiter: ";"
+date_format: "02.01.2006"
+date_formats: ["yyyy-mm-dd"]
+time_zone: Europe/Oslo
+decimal_separator: ","
+encoding: windows-1252
+skip_lines: 2
+header_row_pattern: "^Dato;"
+footer_pattern: "^Saldo"
+columns:
+  - name: Dato
+    kind: date
+    pos: 1
+  - name: Beløp
+    kind: inflow
+    pos: 2
+`)
+	want := csvstatement.Format{
+		Id:               "mybank",
+		Delimiter:        ';',
+		HasHeader:        true,
+		DateFormat:       "02.01.2006",
+		DateFormats:      []string{"yyyy-mm-dd"},
+		TimeZone:         "Europe/Oslo",
+		DecimalSeparator: ',',
+		Encoding:         "windows-1252",
+		SkipLines:        2,
+		HeaderRowPattern: "^Dato;",
+		FooterPattern:    "^Saldo",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Beløp", Kind: csvstatement.FieldInflow, Pos: 2},
+		},
+	}
+
+	got, err := csvstatement.ParseLayout(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLayout:\ngot:\t%+v\nwant:\t%+v", got, want)
+	}
+
+	out, err := csvstatement.MarshalLayout(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roundTrip, err := csvstatement.ParseLayout(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, want) {
+		t.Errorf("round trip:\ngot:\t%+v\nwant:\t%+v", roundTrip, want)
+	}
+}
+
+func TestParseLayout_InvalidDelimiter(t *testing.T) {
+	_, err := csvstatement.ParseLayout([]byte(`delimiter: ";;"`))
+	if err == nil {
+		t.Fatal("expected error for multi-character delimiter")
+	}
+}
+
+func TestNewRegistry_LayoutDirs(t *testing.T) {
+	dir := t.TempDir()
+	override := []byte(`
+id: ynab
+delimiter: ","
+date_format: "01/02/2006"
+decimal_separator: "."
+columns:
+  - name: Date
+    kind: date
+    pos: 1
+`)
+	if err := os.WriteFile(filepath.Join(dir, "ynab.yaml"), override, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "otherbank.yaml"), []byte(`delimiter: ","`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := csvstatement.NewRegistry(&csvstatement.Factory{
+		LayoutDirs: []string{dir, filepath.Join(dir, "does-not-exist")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ynab, err := registry.Get("ynab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ynab.DateFormat != "01/02/2006" {
+		t.Errorf("expected user layout to override built-in, got date format %q", ynab.DateFormat)
+	}
+
+	if _, err := registry.Get("otherbank"); err != nil {
+		t.Errorf("expected layout without id to be registered by file name: %v", err)
+	}
+
+	bulder, err := registry.Get("bulder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bulder.DateFormat != time.DateOnly {
+		t.Errorf("expected built-in bulder layout, got date format %q", bulder.DateFormat)
+	}
+}
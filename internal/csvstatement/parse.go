@@ -3,22 +3,53 @@ package csvstatement
 import (
 	"encoding/csv"
 	"errors"
+	"fincli/internal/charset"
 	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
-	"strconv"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 )
 
 type ParsedStatement struct {
 	Transactions []domain.Transaction
+	// Preamble holds the key/value pairs found in the lines before the
+	// records, such as the account number and the period.
+	Preamble map[string]string
+}
+
+// All returns an iterator over the transactions in the statement, for use
+// with the streaming functions such as [WriteTransactions].
+func (s ParsedStatement) All() iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		for _, txn := range s.Transactions {
+			if !yield(txn, nil) {
+				return
+			}
+		}
+	}
 }
 
 type Parser struct {
-	log    slog.Logger
-	format Format
+	log      slog.Logger
+	format   Format
+	fileName string
+	preamble map[string]string // Of the last statement read by All.
+
+	// The date layouts and time zone of the format, set by All.
+	layouts  []string
+	location *time.Location
+	// The transforms of each column mapping, the composed fields and the
+	// header names of the columns, set by All.
+	transforms []transformFunc
+	fields     []compiledField
+	names      []string
 }
 
 func NewParser(format Format) *Parser {
@@ -29,44 +60,165 @@ func NewParser(format Format) *Parser {
 		parser.log.Warn("Creating parser with empty column mapping. Parsing will return empty transactions")
 	}
 	parser.format = format
+	parser.preamble = map[string]string{}
 	return &parser
 }
 
+// SetLogger replaces the logger used to report problems with the format.
+func (p *Parser) SetLogger(log *slog.Logger) {
+	p.log = *log
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (p *Parser) SetFileName(name string) {
+	p.fileName = name
+}
+
+// Parse reads the whole statement from source into memory. Use [Parser.All]
+// to process large statements one transaction at a time.
 func (p Parser) Parse(source io.Reader) (ParsedStatement, error) {
-	// TODO: Validate that input conforms to format, and is not empty.
 	var result ParsedStatement
-
-	reader := csv.NewReader(source)
-	if p.format.Delimiter != 0 {
-		reader.Comma = p.format.Delimiter
+	for txn, err := range p.All(source) {
+		if err != nil {
+			return ParsedStatement{}, err
+		}
+		result.Transactions = append(result.Transactions, txn)
 	}
+	result.Preamble = p.Preamble()
+	return result, nil
+}
+
+// Preamble returns the key/value pairs found in the preamble of the last
+// statement read by All, see [Format.SkipLines] and [Format.HeaderRowPattern].
+func (p Parser) Preamble() map[string]string {
+	return maps.Clone(p.preamble)
+}
+
+// All returns an iterator over the transactions in source. The statement is
+// read one record at a time as the iterator is advanced.
+//
+// A record that cannot be parsed is yielded as a [*statement.RecordError], and parsing
+// continues with the next record unless the consumer stops. Any other error
+// is yielded last.
+func (p Parser) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		// TODO: Validate that input conforms to format, and is not empty.
+		p.layouts = p.format.dateLayouts()
+		var err error
+		if p.location, err = p.format.location(); err != nil {
+			yield(domain.Transaction{}, fmt.Errorf("parsing statement: %w", err))
+			return
+		}
+
+		if err := p.compile(); err != nil {
+			yield(domain.Transaction{}, fmt.Errorf("parsing statement: %w", err))
+			return
+		}
 
-	if p.format.HasHeader {
-		// TEST: Without header
-		_, err := reader.Read()
+		decoded, err := charset.NewReader(source, p.format.Encoding)
 		if err != nil {
-			return ParsedStatement{}, fmt.Errorf("parsing statement: could not read header. Error: %w", err)
+			yield(domain.Transaction{}, fmt.Errorf("parsing statement: %w", err))
+			return
 		}
-	}
+		preamble, body, err := p.format.splitStatement(decoded)
+		if err != nil {
+			yield(domain.Transaction{}, fmt.Errorf("parsing statement: %w", err))
+			return
+		}
+		if p.preamble != nil {
+			clear(p.preamble)
+			maps.Copy(p.preamble, parsePreamble(preamble, p.format.Delimiter))
+		}
+		// The lines of records are counted from the end of the preamble.
+		offset := len(preamble)
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return result, fmt.Errorf("could not read records. Error: %w", err)
-	}
+		reader := csv.NewReader(body)
+		reader.ReuseRecord = true
+		if p.format.Delimiter != 0 {
+			reader.Comma = p.format.Delimiter
+		}
+
+		if p.format.HasHeader {
+			// TEST: Without header
+			header, err := reader.Read()
+			if err != nil {
+				yield(domain.Transaction{}, fmt.Errorf("parsing statement: could not read header. Error: %w", err))
+				return
+			}
+			if p.format.MatchHeaders {
+				p.format, err = p.format.ResolveHeader(header)
+				if err != nil {
+					yield(domain.Transaction{}, fmt.Errorf("parsing statement: %w", err))
+					return
+				}
+			}
+			p.checkColumnMappings(len(header))
+			p.names = make([]string, len(header))
+			for i, name := range header {
+				p.names[i] = strings.TrimSpace(name)
+			}
+		}
 
-	p.checkColumnMappings(reader.FieldsPerRecord)
+		for first := true; ; first = false {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				recErr := &statement.RecordError{File: p.fileName, Line: offset + parseErr.StartLine, Err: parseErr.Err}
+				if !yield(domain.Transaction{}, recErr) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(domain.Transaction{}, fmt.Errorf("could not read records. Error: %w", err))
+				return
+			}
+			if first && !p.format.HasHeader {
+				p.checkColumnMappings(len(rec))
+			}
 
-	result.Transactions = make([]domain.Transaction, 0, len(records))
+			txn, recErr := p.parseCsvRecord(rec)
+			if recErr != nil {
+				recErr.File = p.fileName
+				line, _ := reader.FieldPos(0)
+				recErr.Line = offset + line
+				if !yield(domain.Transaction{}, recErr) {
+					return
+				}
+				continue
+			}
+			if !yield(*txn, nil) {
+				return
+			}
+		}
+	}
+}
 
-	for _, rec := range records {
-		txn, err := p.parseCsvRecord(rec)
+// compile compiles the column transforms and the composed fields of the
+// format.
+func (p *Parser) compile() error {
+	p.transforms = make([]transformFunc, len(p.format.ColumnMappings))
+	for i, col := range p.format.ColumnMappings {
+		transform, err := compileTransforms(col.Transforms)
 		if err != nil {
-			return ParsedStatement{}, err
+			return fmt.Errorf("column '%s': %w", col.Name, err)
 		}
-		result.Transactions = append(result.Transactions, *txn)
+		p.transforms[i] = transform
 	}
 
-	return result, nil
+	p.fields = nil
+	for _, field := range p.format.Fields {
+		compiled, err := field.compile()
+		if err != nil {
+			return err
+		}
+		p.fields = append(p.fields, compiled)
+	}
+	return nil
 }
 
 // ErrNoColumnMap is returned when the format is not properly configured.
@@ -77,10 +229,10 @@ var ErrNoColumnMap = errors.New("format has no column mappings")
 //
 // If a column mapping specifies a position greater than the number of
 // available fields, a warning is logged. If a column mapping specifies a
-// position of 0 or less, an info message is logged indicating the field will
+// position of 0 or less, a debug message is logged indicating the field will
 // be skipped.
 func (p *Parser) checkColumnMappings(numOfFields int) {
-	p.log.Info("Validating column mapping against CSV")
+	p.log.Debug("Validating column mapping against CSV")
 	for _, col := range p.format.ColumnMappings {
 		if col.Pos > numOfFields {
 			p.log.Warn(
@@ -92,7 +244,7 @@ func (p *Parser) checkColumnMappings(numOfFields int) {
 		}
 
 		if col.Pos <= 0 {
-			p.log.Info(fmt.Sprintf(
+			p.log.Debug(fmt.Sprintf(
 				"Column '%s' has position %d and will be skipped.",
 				col.Name, col.Pos,
 			))
@@ -100,60 +252,202 @@ func (p *Parser) checkColumnMappings(numOfFields int) {
 	}
 }
 
-func (p Parser) parseCsvRecord(record []string) (*domain.Transaction, error) {
+// parseCsvRecord parses a single record. The File and Line of a returned
+// error are left for the caller to set.
+func (p Parser) parseCsvRecord(record []string) (*domain.Transaction, *statement.RecordError) {
 	var txn domain.Transaction
+	sign := 0 // Set by a direction column.
+	var categories, subcategories []string
 	colMap := p.format.ColumnMappings
-	for _, col := range colMap {
-		if col.Pos <= 0 {
+
+	// The currency decides the number of decimals in amounts, so it is
+	// found before any other field.
+	txn.Currency = strings.ToUpper(p.format.Currency)
+	for i, col := range colMap {
+		value, ok := p.columnValue(record, i)
+		if !ok || col.Kind != FieldCurrency {
 			continue
 		}
-		if col.Pos > len(record) {
+		code := strings.TrimSpace(value)
+		if code == "" {
 			continue
 		}
-		value := record[col.Pos-1]
-		if value == "" {
+		if !money.ValidCurrency(code) {
+			return nil, &statement.RecordError{Column: col.Name, Value: value, Err: errors.New("unknown ISO 4217 currency code")}
+		}
+		txn.Currency = strings.ToUpper(code)
+	}
+	amounts := p.format.moneyParser(txn.Currency)
+
+	for i, col := range colMap {
+		value, ok := p.columnValue(record, i)
+		if !ok {
 			continue
 		}
 		switch col.Kind {
-		case FieldDate:
-			date, err := time.Parse(p.format.DateFormat, value)
+		case FieldDate, FieldBookingDate, FieldValueDate:
+			date, err := parseDate(p.layouts, value, p.location)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse date in colum '%s' at position '%d': value='%s'. Error: %w", col.Name, col.Pos, value, err)
+				return nil, &statement.RecordError{Column: col.Name, Value: value, Err: fmt.Errorf("could not parse date: %w", err)}
+			}
+			switch col.Kind {
+			case FieldDate:
+				txn.Date = date
+			case FieldBookingDate:
+				txn.BookingDate = date
+			case FieldValueDate:
+				txn.ValueDate = date
 			}
-			txn.Date = date
 		case FieldPayee:
 			txn.CounterpartName = value
 		case FieldMemo:
 			txn.Description = value
+		case FieldCategory:
+			categories = append(categories, value)
+		case FieldSubcategory:
+			subcategories = append(subcategories, value)
+		case FieldTags:
+			for _, tag := range strings.Split(value, tagSeparator) {
+				if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(txn.Tags, tag) {
+					txn.Tags = append(txn.Tags, tag)
+				}
+			}
+		case FieldNotes:
+			txn.Notes = value
 		case FieldInflow:
-			amount, err := strconv.Atoi(normalizeDecimal(value))
+			amount, err := parseUnsigned(amounts, value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse inflow value at column position '%d' with value '%s': %w", col.Pos, value, err)
+				return nil, &statement.RecordError{Column: col.Name, Value: value, Err: fmt.Errorf("could not parse inflow: %w", err)}
 			}
 			txn.Amount += amount
 		case FieldOutflow:
-			amount, err := strconv.Atoi(normalizeDecimal(value))
+			amount, err := parseUnsigned(amounts, value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse outflow value at column position '%d' with value '%s': %w", col.Pos, value, err)
+				return nil, &statement.RecordError{Column: col.Name, Value: value, Err: fmt.Errorf("could not parse outflow: %w", err)}
 			}
 			txn.Amount -= amount
+		case FieldAmount:
+			amount, err := amounts.Parse(value)
+			if err != nil {
+				return nil, &statement.RecordError{Column: col.Name, Value: value, Err: fmt.Errorf("could not parse amount: %w", err)}
+			}
+			txn.Amount += amount
+		case FieldDirection:
+			switch {
+			case containsFold(col.debitValues(), value):
+				sign = -1
+			case containsFold(col.creditValues(), value):
+				sign = 1
+			default:
+				return nil, &statement.RecordError{Column: col.Name, Value: value, Err: errors.New("unknown debit/credit indicator")}
+			}
+		}
+	}
+
+	txn.Category = joinCategory(append(categories, subcategories...))
+
+	if len(p.fields) > 0 {
+		columns := p.columns(record)
+		for _, field := range p.fields {
+			value, err := field.value(columns)
+			if err != nil {
+				return nil, &statement.RecordError{Column: string(field.kind), Err: fmt.Errorf("could not compose field: %w", err)}
+			}
+			switch field.kind {
+			case FieldPayee:
+				txn.CounterpartName = value
+			case FieldMemo:
+				txn.Description = value
+			}
 		}
 	}
 
+	if sign != 0 && (txn.Amount < 0) != (sign < 0) {
+		txn.Amount = -txn.Amount
+	}
+	if txn.Date.IsZero() {
+		txn.Date = txn.BookingDate
+	}
+	if txn.Date.IsZero() {
+		txn.Date = txn.ValueDate
+	}
 	return &txn, nil
 }
 
-// normalizeDecimal removes all spaces, commas, dots, and sign characters (+/-)
-// from the input string.
-//
-// This is useful for normalizing decimal numbers (with assumed precision 2)
-// from various locales so they can be parsed as integers representing the
-// smallest currency unit (e.g., cents).
-func normalizeDecimal(dirty string) (clean string) {
-	clean = strings.ReplaceAll(dirty, " ", "")
-	clean = strings.ReplaceAll(clean, ",", "")
-	clean = strings.ReplaceAll(clean, ".", "")
-	clean = strings.ReplaceAll(clean, "+", "")
-	clean = strings.ReplaceAll(clean, "-", "")
-	return clean
+// tagSeparator separates the tags in a FieldTags column.
+const tagSeparator = ","
+
+// joinCategory joins the levels of a category, leaving out empty levels.
+func joinCategory(levels []string) string {
+	var parts []string
+	for _, level := range levels {
+		if level = strings.TrimSpace(level); level != "" {
+			parts = append(parts, level)
+		}
+	}
+	return strings.Join(parts, domain.CategorySeparator)
+}
+
+// columnValue returns the value of the i-th column mapping in record, after
+// the transforms of the column. It reports false if the column is not present
+// in the record, or the value is empty.
+func (p Parser) columnValue(record []string, i int) (string, bool) {
+	value, ok := fieldValue(record, p.format.ColumnMappings[i])
+	if ok && i < len(p.transforms) && p.transforms[i] != nil {
+		value = p.transforms[i](value)
+		ok = value != ""
+	}
+	return value, ok
+}
+
+// columns returns the values of record by the header names of the columns,
+// and by the names of the column mappings, for the templates of composed
+// fields.
+func (p Parser) columns(record []string) map[string]string {
+	columns := make(map[string]string, len(record))
+	for i, name := range p.names {
+		if i < len(record) && name != "" {
+			columns[name] = record[i]
+		}
+	}
+	for i, col := range p.format.ColumnMappings {
+		if value, ok := p.columnValue(record, i); ok {
+			columns[col.Name] = value
+		}
+	}
+	return columns
+}
+
+// fieldValue returns the value of col in record. It reports false if the
+// column is not present in the record, or the value is empty.
+func fieldValue(record []string, col TransactionColumn) (string, bool) {
+	if col.Pos <= 0 || col.Pos > len(record) {
+		return "", false
+	}
+	value := record[col.Pos-1]
+	return value, value != ""
+}
+
+// containsFold reports whether values contains value, ignoring case and
+// surrounding spaces.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUnsigned parses the value of an inflow or outflow column. The
+// direction is given by the column, so the sign of the value is ignored.
+func parseUnsigned(amounts money.Parser, value string) (int, error) {
+	amount, err := amounts.Parse(value)
+	if err != nil {
+		return 0, err
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	return amount, nil
 }
@@ -86,7 +86,10 @@ func Test_Bulder(t *testing.T) {
 		},
 	}
 
-	registry := csvstatement.NewRegistry(nil)
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	format, err := registry.Get("bulder")
 	if err != nil {
 		t.Fatal(err)
@@ -131,3 +134,57 @@ func checkEqual(want, got domain.Transaction) error {
 	}
 	return nil
 }
+
+func TestParser_Encoding(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:        ';',
+		HasHeader:        true,
+		MatchHeaders:     true,
+		DateFormat:       time.DateOnly,
+		DecimalSeparator: ',',
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Dato", Kind: csvstatement.FieldDate},
+			{Name: "Beløp", Kind: csvstatement.FieldAmount},
+			{Name: "Tekst", Kind: csvstatement.FieldMemo},
+		},
+	}
+	text := "Dato;Beløp;Tekst\n2025-01-02;100,00;Inn på konto\n"
+
+	tests := []struct {
+		name     string
+		encoding string
+		data     []byte
+	}{
+		{name: "utf-8", data: []byte(text)},
+		{name: "utf-8 with bom", data: append([]byte{0xEF, 0xBB, 0xBF}, text...)},
+		{name: "windows-1252", encoding: "windows-1252", data: []byte(strings.NewReplacer("ø", "\xf8", "å", "\xe5").Replace(text))},
+		{name: "utf-16 with bom", data: utf16LE(text)},
+		{name: "bom over encoding", encoding: "windows-1252", data: utf16LE(text)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := format
+			format.Encoding = tt.encoding
+			result, err := csvstatement.NewParser(format).Parse(strings.NewReader(string(tt.data)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Transactions) != 1 {
+				t.Fatalf("expected 1 transaction, got %d", len(result.Transactions))
+			}
+			if got := result.Transactions[0].Description; got != "Inn på konto" {
+				t.Errorf("got description %q", got)
+			}
+		})
+	}
+}
+
+// utf16LE encodes s as UTF-16LE with a byte order mark.
+func utf16LE(s string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, r := range s {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	return data
+}
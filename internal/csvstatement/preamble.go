@@ -0,0 +1,151 @@
+package csvstatement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrHeaderRowNotFound is returned when no line of a statement matches the
+// HeaderRowPattern of its format.
+var ErrHeaderRowNotFound = errors.New("no line matches the header row pattern")
+
+// splitStatement reads the preamble of a statement in the format from source:
+// the first SkipLines lines, and then, if the format has a HeaderRowPattern,
+// the lines before the first line matched by it. It returns the lines of the
+// preamble, and a reader of the rest of the statement that ends before the
+// first line matched by the FooterPattern of the format.
+func (f Format) splitStatement(source io.Reader) ([]string, io.Reader, error) {
+	lines := bufio.NewReader(source)
+	var preamble []string
+	readLine := func() (string, error) {
+		line, err := lines.ReadString('\n')
+		if errors.Is(err, io.EOF) && line != "" {
+			err = nil
+		}
+		return line, err
+	}
+
+	for range f.SkipLines {
+		line, err := readLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		preamble = append(preamble, line)
+	}
+
+	var body io.Reader = lines
+	if f.HeaderRowPattern != "" {
+		pattern, err := regexp.Compile(f.HeaderRowPattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid header row pattern: %w", err)
+		}
+		for {
+			line, err := readLine()
+			if errors.Is(err, io.EOF) {
+				return nil, nil, fmt.Errorf("%w %q", ErrHeaderRowNotFound, f.HeaderRowPattern)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if pattern.MatchString(strings.TrimRight(line, "\r\n")) {
+				body = io.MultiReader(strings.NewReader(line), lines)
+				break
+			}
+			preamble = append(preamble, line)
+		}
+	}
+
+	if f.FooterPattern != "" {
+		pattern, err := regexp.Compile(f.FooterPattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid footer pattern: %w", err)
+		}
+		body = &footerReader{lines: bufio.NewReader(body), pattern: pattern}
+	}
+	return preamble, body, nil
+}
+
+// footerReader reads lines until a line matched by pattern, which starts the
+// footer of a statement.
+type footerReader struct {
+	lines   *bufio.Reader
+	pattern *regexp.Regexp
+	line    []byte // The rest of the line being read.
+	done    bool
+}
+
+func (r *footerReader) Read(p []byte) (int, error) {
+	for len(r.line) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		line, err := r.lines.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			r.done = true
+		} else if err != nil {
+			return 0, err
+		}
+		if len(line) > 0 && r.pattern.Match(bytes.TrimRight(line, "\r\n")) {
+			r.done = true
+			return 0, io.EOF
+		}
+		r.line = line
+	}
+	n := copy(p, r.line)
+	r.line = r.line[n:]
+	return n, nil
+}
+
+// parsePreamble returns the key/value pairs in the preamble lines of a
+// statement, such as the account number and the period. A line holds a pair
+// if it has a key field followed by one or more value fields, separated by
+// the delimiter of the format, or a single field with a colon between the
+// key and the value. Other lines are ignored.
+func parsePreamble(lines []string, delimiter rune) map[string]string {
+	values := map[string]string{}
+	for _, line := range lines {
+		reader := csv.NewReader(strings.NewReader(line))
+		if delimiter != 0 {
+			reader.Comma = delimiter
+		}
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		record, err := reader.Read()
+		if err != nil {
+			continue
+		}
+
+		var fields []string
+		for _, field := range record {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 1 {
+			key, value, ok := strings.Cut(fields[0], ":")
+			if !ok {
+				continue
+			}
+			fields = []string{key, value}
+		}
+		if len(fields) < 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(strings.TrimSuffix(fields[0], ":"))
+		value := strings.TrimSpace(strings.Join(fields[1:], " "))
+		if key != "" && value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
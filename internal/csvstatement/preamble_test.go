@@ -0,0 +1,122 @@
+package csvstatement_test
+
+import (
+	"errors"
+	"fincli/internal/csvstatement"
+	"fincli/internal/statement"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+// preambleFormat is a statement with account details before the header row,
+// and a balance row after the records.
+var preambleFormat = csvstatement.Format{
+	Id:               "preamble",
+	Delimiter:        ';',
+	HasHeader:        true,
+	DateFormat:       time.DateOnly,
+	DecimalSeparator: ',',
+	HeaderRowPattern: "^Dato;",
+	FooterPattern:    "^Saldo",
+	ColumnMappings: []csvstatement.TransactionColumn{
+		{Name: "Dato", Kind: csvstatement.FieldDate, Pos: 1},
+		{Name: "Tekst", Kind: csvstatement.FieldMemo, Pos: 2},
+		{Name: "Beløp", Kind: csvstatement.FieldAmount, Pos: 3},
+	},
+}
+
+const preambleStatement = `Kontoutskrift
+Kontonummer;1234.56.78901
+Periode: 01.01.2025 - 31.01.2025
+
+Dato;Tekst;Beløp
+2025-01-02;Lønn;100,00
+2025-01-03;Kiwi;-12,50
+Saldo;;87,50
+Sum inn;;100,00
+`
+
+func TestParser_Preamble(t *testing.T) {
+	tests := []struct {
+		name   string
+		format func(csvstatement.Format) csvstatement.Format
+	}{
+		{
+			name:   "header row pattern",
+			format: func(f csvstatement.Format) csvstatement.Format { return f },
+		},
+		{
+			name: "skip lines",
+			format: func(f csvstatement.Format) csvstatement.Format {
+				f.HeaderRowPattern = ""
+				f.SkipLines = 4
+				return f
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := csvstatement.NewParser(tt.format(preambleFormat)).Parse(strings.NewReader(preambleStatement))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Transactions) != 2 {
+				t.Fatalf("expected 2 transactions, got %d", len(result.Transactions))
+			}
+			if got := result.Transactions[1].Amount; got != -1250 {
+				t.Errorf("got amount %d of the last transaction", got)
+			}
+
+			want := map[string]string{
+				"Kontonummer": "1234.56.78901",
+				"Periode":     "01.01.2025 - 31.01.2025",
+			}
+			if !maps.Equal(result.Preamble, want) {
+				t.Errorf("got preamble %v, want %v", result.Preamble, want)
+			}
+		})
+	}
+}
+
+func TestParser_Preamble_RecordErrorLine(t *testing.T) {
+	data := strings.Replace(preambleStatement, "-12,50", "abc", 1)
+	var recErr *statement.RecordError
+	for _, err := range csvstatement.NewParser(preambleFormat).All(strings.NewReader(data)) {
+		if errors.As(err, &recErr) {
+			break
+		}
+	}
+	if recErr == nil {
+		t.Fatal("expected a record error")
+	}
+	if recErr.Line != 7 {
+		t.Errorf("got error on line %d, want 7", recErr.Line)
+	}
+}
+
+func TestParser_Preamble_HeaderRowNotFound(t *testing.T) {
+	_, err := csvstatement.NewParser(preambleFormat).Parse(strings.NewReader("Kontonummer;1234\n"))
+	if !errors.Is(err, csvstatement.ErrHeaderRowNotFound) {
+		t.Errorf("expected ErrHeaderRowNotFound, got %v", err)
+	}
+}
+
+func TestFormatRegistry_Detect_Preamble(t *testing.T) {
+	registry, err := csvstatement.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(*registry)[preambleFormat.Id] = preambleFormat
+
+	got, err := registry.Detect([]byte(preambleStatement))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Id != preambleFormat.Id {
+		t.Errorf("detected %q, want %q", got.Id, preambleFormat.Id)
+	}
+}
@@ -0,0 +1,158 @@
+package csvstatement
+
+import (
+	"fincli/internal/charset"
+	"fincli/internal/money"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Problem is an issue with a Format found by [Format.Validate].
+type Problem struct {
+	Message string
+
+	// Read and Write tell whether the problem prevents the format from being
+	// used to parse or to write statements.
+	Read, Write bool
+}
+
+func (p Problem) String() string {
+	return p.Message
+}
+
+// Validate checks that the format can be used to parse and write statements,
+// and returns the problems found.
+func (f Format) Validate() []Problem {
+	var problems []Problem
+
+	if len(f.ColumnMappings) == 0 {
+		return []Problem{{Message: "format has no column mappings", Read: true, Write: true}}
+	}
+
+	if f.ThousandsSeparator != 0 && f.ThousandsSeparator == f.DecimalSeparator {
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("thousands separator and decimal separator are both %q", f.DecimalSeparator),
+			Read:    true, Write: true,
+		})
+	}
+
+	if f.Currency != "" && !money.ValidCurrency(f.Currency) {
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("currency '%s' is not an ISO 4217 currency code", f.Currency),
+			Read:    true, Write: true,
+		})
+	}
+
+	if _, err := charset.Lookup(f.Encoding); err != nil {
+		problems = append(problems, Problem{Message: err.Error(), Read: true, Write: true})
+	}
+
+	if _, err := f.location(); err != nil {
+		problems = append(problems, Problem{Message: err.Error(), Read: true, Write: true})
+	}
+
+	if f.SkipLines < 0 {
+		problems = append(problems, Problem{Message: fmt.Sprintf("number of lines to skip is negative: %d", f.SkipLines), Read: true})
+	}
+	if _, err := regexp.Compile(f.HeaderRowPattern); err != nil {
+		problems = append(problems, Problem{Message: fmt.Sprintf("invalid header row pattern: %v", err), Read: true})
+	}
+	if _, err := regexp.Compile(f.FooterPattern); err != nil {
+		problems = append(problems, Problem{Message: fmt.Sprintf("invalid footer pattern: %v", err), Read: true})
+	}
+
+	if f.MatchHeaders && !f.HasHeader {
+		problems = append(problems, Problem{Message: "columns can only be matched by header name when the format has a header", Read: true})
+	}
+
+	for _, field := range f.Fields {
+		if _, err := field.compile(); err != nil {
+			problems = append(problems, Problem{Message: err.Error(), Read: true})
+		}
+	}
+
+	var hasDate, hasAmount bool
+	byPos := map[int][]string{}
+	for _, col := range f.ColumnMappings {
+		if col.HeaderPattern != "" {
+			if _, err := regexp.Compile(col.HeaderPattern); err != nil {
+				problems = append(problems, Problem{
+					Message: fmt.Sprintf("column '%s' has invalid header pattern: %v", col.Name, err),
+					Read:    true,
+				})
+			}
+		}
+
+		if _, err := compileTransforms(col.Transforms); err != nil {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("column '%s': %v", col.Name, err),
+				Read:    true,
+			})
+		}
+
+		switch col.Kind {
+		case FieldDate, FieldBookingDate, FieldValueDate:
+			hasDate = true
+		case FieldInflow, FieldOutflow, FieldAmount:
+			hasAmount = true
+		case FieldPayee, FieldMemo, FieldDirection, FieldCurrency,
+			FieldCategory, FieldSubcategory, FieldTags, FieldNotes:
+		default:
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("column '%s' has unknown field kind '%s'", col.Name, col.Kind),
+				Read:    true, Write: true,
+			})
+		}
+
+		if col.Pos <= 0 {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("column '%s' has position %d, but positions start at 1", col.Name, col.Pos),
+				Write:   true,
+			})
+			continue
+		}
+		byPos[col.Pos] = append(byPos[col.Pos], col.Name)
+	}
+
+	if !hasDate {
+		problems = append(problems, Problem{Message: "no column of kind 'date', 'booking_date' or 'value_date'", Read: true, Write: true})
+	}
+	if !hasAmount {
+		problems = append(problems, Problem{Message: "no column of kind 'inflow', 'outflow' or 'amount'", Read: true, Write: true})
+	}
+
+	positions := make([]int, 0, len(byPos))
+	for pos := range byPos {
+		positions = append(positions, pos)
+	}
+	slices.Sort(positions)
+	for _, pos := range positions {
+		if names := byPos[pos]; len(names) > 1 {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("columns %q share position %d", names, pos),
+				Write:   true,
+			})
+		}
+	}
+	for pos := 1; pos <= len(f.ColumnMappings); pos++ {
+		if _, ok := byPos[pos]; !ok {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("no column at position %d; positions must be 1 to %d without gaps", pos, len(f.ColumnMappings)),
+				Write:   true,
+			})
+		}
+	}
+
+	return problems
+}
+
+// CanRead reports whether the format can be used to parse statements.
+func (f Format) CanRead() bool {
+	return !slices.ContainsFunc(f.Validate(), func(p Problem) bool { return p.Read })
+}
+
+// CanWrite reports whether the format can be used to write statements.
+func (f Format) CanWrite() bool {
+	return !slices.ContainsFunc(f.Validate(), func(p Problem) bool { return p.Write })
+}
@@ -2,21 +2,74 @@ package csvstatement
 
 import (
 	"encoding/csv"
+	"fincli/internal/charset"
 	"fincli/internal/domain"
+	"fincli/internal/money"
 	"fmt"
 	"io"
+	"iter"
+	"strings"
+	"time"
 )
 
+// Writer writes transactions as CSV in a format. It implements
+// [statement.Writer].
+type Writer struct {
+	format Format
+}
+
+func NewWriter(format Format) *Writer {
+	return &Writer{format: format}
+}
+
+// WriteAll writes the transactions from txns to target, see
+// [WriteTransactions].
+func (w *Writer) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	return WriteTransactions(target, txns, w.format)
+}
+
+// WriteStatement writes all transactions in statement as CSV in the given
+// format.
 func WriteStatement(writer io.Writer, statement ParsedStatement, format Format) error {
+	return WriteTransactions(writer, statement.All(), format)
+}
+
+// WriteTransactions writes the transactions from txns as CSV in the given
+// format, one at a time as they are yielded. It stops at the first error
+// yielded by txns and returns it.
+func WriteTransactions(writer io.Writer, txns iter.Seq2[domain.Transaction, error], format Format) error {
+	for _, problem := range format.Validate() {
+		if problem.Write {
+			return fmt.Errorf("format '%s' cannot be written: %s", format.Id, problem)
+		}
+	}
+
+	var encoded io.WriteCloser
+	if !charset.IsUTF8(format.Encoding) {
+		var err error
+		encoded, err = charset.NewWriter(writer, format.Encoding)
+		if err != nil {
+			return fmt.Errorf("format '%s' cannot be written: %w", format.Id, err)
+		}
+		writer = encoded
+	}
+
 	csvwriter := csv.NewWriter(writer)
-	defer csvwriter.Flush()
+	if format.Delimiter != 0 {
+		csvwriter.Comma = format.Delimiter
+	}
 	if format.HasHeader {
 		if err := writeHeader(csvwriter, format.ColumnMappings); err != nil {
 			return fmt.Errorf("could not write CSV header: %w", err)
 		}
 	}
 
-	for idx, txn := range statement.Transactions {
+	idx := 0
+	for txn, err := range txns {
+		if err != nil {
+			csvwriter.Flush()
+			return err
+		}
 		if err := writeRecord(
 			csvwriter,
 			txn,
@@ -24,6 +77,17 @@ func WriteStatement(writer io.Writer, statement ParsedStatement, format Format)
 		); err != nil {
 			return fmt.Errorf("could not write transaction %d as CSV record: %w", idx, err)
 		}
+		idx++
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write CSV: %w", err)
+	}
+	if encoded != nil {
+		if err := encoded.Close(); err != nil {
+			return fmt.Errorf("could not write CSV: %w", err)
+		}
 	}
 	return nil
 }
@@ -51,26 +115,59 @@ func writeRecord(
 func constructRecord(txn domain.Transaction, format Format) ([]string, error) {
 	colMap := format.ColumnMappings
 	record := make([]string, len(colMap))
+	category, subcategory := txn.Category, ""
+	if format.hasKind(FieldSubcategory) {
+		category, subcategory, _ = strings.Cut(txn.Category, domain.CategorySeparator)
+	}
 	for _, col := range colMap {
 		var value string
 		switch col.Kind {
 		case FieldDate:
-			value = txn.Date.Format(format.DateFormat)
+			value = format.formatTime(txn.Date)
+		case FieldBookingDate:
+			value = formatDate(txn.BookingDate, format)
+		case FieldValueDate:
+			value = formatDate(txn.ValueDate, format)
+		case FieldCurrency:
+			value = txn.Currency
+			if value == "" {
+				value = format.Currency
+			}
 		case FieldPayee:
 			value = txn.CounterpartName
 		case FieldMemo:
 			value = txn.Description
+		case FieldCategory:
+			value = category
+		case FieldSubcategory:
+			value = subcategory
+		case FieldTags:
+			value = strings.Join(txn.Tags, tagSeparator)
+		case FieldNotes:
+			value = txn.Notes
 		case FieldInflow:
 			if txn.Amount > 0 {
-				value = formatAmount(txn.Amount, format)
+				value = formatAmount(txn.Amount, txn, format)
 			} else {
-				value = formatAmount(0, format)
+				value = formatAmount(0, txn, format)
 			}
 		case FieldOutflow:
 			if txn.Amount < 0 {
-				value = formatAmount(-txn.Amount, format)
+				value = formatAmount(-txn.Amount, txn, format)
 			} else {
-				value = formatAmount(0, format)
+				value = formatAmount(0, txn, format)
+			}
+		case FieldAmount:
+			if format.hasKind(FieldDirection) {
+				value = formatAmount(abs(txn.Amount), txn, format)
+			} else {
+				value = formatAmount(txn.Amount, txn, format)
+			}
+		case FieldDirection:
+			if txn.Amount < 0 {
+				value = col.debitValues()[0]
+			} else {
+				value = col.creditValues()[0]
 			}
 		default:
 			return nil, fmt.Errorf("could not construct record field: unknown field kind '%s'", col.Kind)
@@ -80,8 +177,27 @@ func constructRecord(txn domain.Transaction, format Format) ([]string, error) {
 	return record, nil
 }
 
-func formatAmount(value int, format Format) string {
-	major := value / 100
-	minor := value % 100
-	return fmt.Sprintf("%d%c%02d", major, format.DecimalSeparator, minor)
+// formatAmount formats value with the number of decimals of the currency of
+// txn, or of the format if the transaction has no currency.
+func formatAmount(value int, txn domain.Transaction, format Format) string {
+	currency := txn.Currency
+	if currency == "" {
+		currency = format.Currency
+	}
+	return money.Format(value, money.Exponent(currency), format.DecimalSeparator)
+}
+
+// formatDate formats date, or returns an empty string if date is not set.
+func formatDate(date time.Time, format Format) string {
+	if date.IsZero() {
+		return ""
+	}
+	return format.formatTime(date)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
 }
@@ -46,7 +46,10 @@ func Test_Write(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			registry := csvstatement.NewRegistry(nil)
+			registry, err := csvstatement.NewRegistry(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
 			format, err := registry.Get(tt.formatId)
 			if err != nil {
 				t.Fatal(err)
@@ -83,3 +86,101 @@ func Test_Write(t *testing.T) {
 		})
 	}
 }
+
+func Test_WriteParseRoundTrip(t *testing.T) {
+	amounts := []int{0, 5, -5, 50, -1234, 123456789, -100}
+
+	tests := []struct {
+		name   string
+		format csvstatement.Format
+	}{
+		{
+			name: "comma decimal NOK",
+			format: csvstatement.Format{
+				Delimiter: ';', HasHeader: true, DateFormat: time.DateOnly,
+				DecimalSeparator: ',', ThousandsSeparator: '.', Currency: "NOK",
+			},
+		},
+		{
+			name: "JPY without decimals",
+			format: csvstatement.Format{
+				Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly,
+				DecimalSeparator: '.', Currency: "JPY",
+			},
+		},
+		{
+			name: "BHD with three decimals",
+			format: csvstatement.Format{
+				Delimiter: ',', HasHeader: true, DateFormat: time.DateOnly,
+				DecimalSeparator: '.', Currency: "BHD",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			format.ColumnMappings = []csvstatement.TransactionColumn{
+				{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+				{Name: "Inflow", Kind: csvstatement.FieldInflow, Pos: 2},
+				{Name: "Outflow", Kind: csvstatement.FieldOutflow, Pos: 3},
+			}
+
+			var statement csvstatement.ParsedStatement
+			for _, amount := range amounts {
+				statement.Transactions = append(statement.Transactions, domain.Transaction{
+					Date:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Amount: amount,
+				})
+			}
+
+			var out strings.Builder
+			if err := csvstatement.WriteStatement(&out, statement, format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := csvstatement.NewParser(format).Parse(strings.NewReader(out.String()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out.String())
+			}
+			if len(got.Transactions) != len(amounts) {
+				t.Fatalf("expected %d transactions, got %d", len(amounts), len(got.Transactions))
+			}
+			for i, amount := range amounts {
+				if got.Transactions[i].Amount != amount {
+					t.Errorf("transaction %d: got amount %d, want %d", i, got.Transactions[i].Amount, amount)
+				}
+			}
+		})
+	}
+}
+
+func Test_WriteEncoding(t *testing.T) {
+	format := csvstatement.Format{
+		Delimiter:        ';',
+		HasHeader:        false,
+		DateFormat:       time.DateOnly,
+		DecimalSeparator: '.',
+		Encoding:         "windows-1252",
+		ColumnMappings: []csvstatement.TransactionColumn{
+			{Name: "Date", Kind: csvstatement.FieldDate, Pos: 1},
+			{Name: "Memo", Kind: csvstatement.FieldMemo, Pos: 2},
+			{Name: "Amount", Kind: csvstatement.FieldAmount, Pos: 3},
+		},
+	}
+	txns := csvstatement.ParsedStatement{Transactions: []domain.Transaction{
+		{Date: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), Description: "Inn på konto", Amount: 100},
+	}}
+
+	var buf strings.Builder
+	if err := csvstatement.WriteStatement(&buf, txns, format); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "2025-01-02;Inn p\xe5 konto;1.00\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	txns.Transactions[0].Description = "Kaffe ☕"
+	if err := csvstatement.WriteStatement(&buf, txns, format); err == nil {
+		t.Error("expected an error for a character that windows-1252 cannot represent")
+	}
+}
@@ -5,10 +5,24 @@ import "time"
 // Transaction represents a single financial transaction, such as an entry from
 // a bank statement.
 type Transaction struct {
+	// Id is the identifier the bank has given the transaction, such as the
+	// FITID in OFX, if known.
+	Id string
+
+	// EndToEndId is the reference the payer gave the payment, which follows
+	// it from payer to payee, such as the EndToEndId of ISO 20022, if known.
+	EndToEndId string
+
 	// Date is the date and time when the transaction occurred.
 	Date time.Time
 
-	// NOTE: Consider adding BookingDate and ValueDate in stead
+	// BookingDate is the date the transaction was booked on the account, if
+	// known.
+	BookingDate time.Time
+
+	// ValueDate is the date the funds became available or started to accrue
+	// interest, if known.
+	ValueDate time.Time
 
 	// CounterpartName is the name of the person or entity receiving or sending funds.
 	CounterpartName string
@@ -20,6 +34,26 @@ type Transaction struct {
 	// The value  is an integer that represents tha smalles currency unit (e.g., cents).
 	Amount int
 
-	// Currency is the ISO4217 code of the currency
-	// Currency string
+	// Category is the category of the transaction, such as "Food:Groceries",
+	// where CategorySeparator separates a category from its subcategory. It
+	// is empty if the transaction is not categorized.
+	Category string
+
+	// Currency is the ISO4217 code of the currency, such as "NOK". It is
+	// empty if the currency is unknown.
+	Currency string
+
+	// Account is the number or IBAN of the account the transaction belongs
+	// to, if the statement tells.
+	Account string
+
+	// Tags are labels of the transaction, such as "vacation".
+	Tags []string
+
+	// Notes are free-form notes about the transaction, kept apart from the
+	// description given by the bank.
+	Notes string
 }
+
+// CategorySeparator separates the levels of a hierarchical category.
+const CategorySeparator = ":"
@@ -1,7 +1,10 @@
 // Package iostreams provides a simple abstraction for standard input, output, and error streams.
 package iostreams
 
-import "io"
+import (
+	"io"
+	"os"
+)
 
 // IOStreams groups standard input, output, and error streams.
 // It can be used to inject custom readers and writers for testing or redirection.
@@ -10,3 +13,13 @@ type IOStreams struct {
 	Out io.Writer // Out is the output stream, typically os.Stdout.
 	Err io.Writer // Err is the error output stream, typically os.Stderr.
 }
+
+// IsStdinTTY reports whether In is a terminal, as opposed to a pipe or a file.
+func (s *IOStreams) IsStdinTTY() bool {
+	f, ok := s.In.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
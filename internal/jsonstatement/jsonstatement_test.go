@@ -0,0 +1,152 @@
+package jsonstatement_test
+
+import (
+	"bytes"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/jsonstatement"
+	"fincli/internal/statement"
+	"fincli/internal/statement/statementtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var transactions = []domain.Transaction{
+	{
+		Id:              "REF1",
+		EndToEndId:      "INV-17",
+		Date:            time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		BookingDate:     time.Date(2025, time.January, 2, 10, 30, 0, 0, time.FixedZone("", 3600)),
+		ValueDate:       time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+		CounterpartName: "Kiwi",
+		Description:     "Groceries",
+		Category:        "Food:Groceries",
+		Amount:          -11234,
+		Currency:        "NOK",
+		Account:         "1234.56.78901",
+		Tags:            []string{"weekly"},
+		Notes:           "Split with Kari",
+	},
+	{
+		Date:   time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
+		Amount: 500,
+	},
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		ndjson bool
+		txns   []domain.Transaction
+		want   string
+	}{
+		{
+			name: "array",
+			txns: transactions,
+			want: `[
+  {"version":1,"id":"REF1","end_to_end_id":"INV-17","date":"2025-01-02","booking_date":"2025-01-02T10:30:00+01:00","value_date":"2025-01-03","counterpart_name":"Kiwi","description":"Groceries","category":"Food:Groceries","amount":-11234,"currency":"NOK","account":"1234.56.78901","tags":["weekly"],"notes":"Split with Kari"},
+  {"version":1,"date":"2025-01-15","amount":500}
+]
+`,
+		},
+		{
+			name: "empty array",
+			want: "[\n]\n",
+		},
+		{
+			name:   "ndjson",
+			ndjson: true,
+			txns:   transactions[1:],
+			want:   `{"version":1,"date":"2025-01-15","amount":500}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := jsonstatement.NewWriter(tt.ndjson).WriteAll(&buf, statementtest.Seq(tt.txns)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, ndjson := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := jsonstatement.NewWriter(ndjson).WriteAll(&buf, statementtest.Seq(transactions)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := statementtest.Collect(t, jsonstatement.NewReader(ndjson).All(&buf))
+		if len(got) != len(transactions) {
+			t.Fatalf("expected %d transactions, got %d", len(transactions), len(got))
+		}
+		for i, want := range transactions {
+			// Compare the times as instants, since the zone name is not kept.
+			if !got[i].BookingDate.Equal(want.BookingDate) {
+				t.Errorf("ndjson=%t, transaction %d: booking date %v, want %v", ndjson, i, got[i].BookingDate, want.BookingDate)
+			}
+			got[i].BookingDate, want.BookingDate = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got[i], want) {
+				t.Errorf("ndjson=%t, transaction %d:\ngot:\t%+v\nwant:\t%+v", ndjson, i, got[i], want)
+			}
+		}
+	}
+}
+
+func TestReader_RecordError(t *testing.T) {
+	data := `{"version":1,"date":"2025-01-02","amount":100}
+
+{"version":1,"date":"02.01.2025","amount":200}
+{"version":2,"date":"2025-01-02","amount":300}
+{"version":1,"date":"2025-01-04","amount":400}
+`
+	reader := jsonstatement.NewReader(true)
+	reader.SetFileName("txns.ndjson")
+
+	var (
+		amounts []int
+		errs    []*statement.RecordError
+	)
+	for txn, err := range reader.All(strings.NewReader(data)) {
+		var recErr *statement.RecordError
+		if errors.As(err, &recErr) {
+			errs = append(errs, recErr)
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		amounts = append(amounts, txn.Amount)
+	}
+
+	if !reflect.DeepEqual(amounts, []int{100, 400}) {
+		t.Errorf("got amounts %v", amounts)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(errs))
+	}
+	if errs[0].File != "txns.ndjson" || errs[0].Line != 3 || errs[0].Column != "date" {
+		t.Errorf("unexpected first error: %v", errs[0])
+	}
+	if errs[1].Line != 4 || errs[1].Column != "version" {
+		t.Errorf("unexpected second error: %v", errs[1])
+	}
+}
+
+func TestReader_Detect(t *testing.T) {
+	array := []byte("\n[\n  {\"version\":1,\"date\":\"2025-01-02\",\"amount\":1}")
+	ndjson := []byte("{\"version\":1,\"date\":\"2025-01-02\",\"amount\":1}\n")
+
+	if !jsonstatement.NewReader(false).Detect(array) || jsonstatement.NewReader(false).Detect(ndjson) {
+		t.Error("expected only the array to be detected as JSON")
+	}
+	if !jsonstatement.NewReader(true).Detect(ndjson) || jsonstatement.NewReader(true).Detect(array) {
+		t.Error("expected only NDJSON to be detected as NDJSON")
+	}
+}
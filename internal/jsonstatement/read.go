@@ -0,0 +1,153 @@
+package jsonstatement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+)
+
+// Reader reads transactions from a JSON array or from NDJSON. It implements
+// [statement.Reader] and [statement.Detector].
+type Reader struct {
+	// NDJSON tells which of the two the reader detects. Both are read by All.
+	NDJSON bool
+
+	fileName string
+}
+
+func NewReader(ndjson bool) *Reader {
+	return &Reader{NDJSON: ndjson}
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (r *Reader) SetFileName(name string) {
+	r.fileName = name
+}
+
+// Detect reports whether sample starts with a JSON array (or, for NDJSON, a
+// JSON object) of transactions.
+func (r *Reader) Detect(sample []byte) bool {
+	sample = bytes.TrimLeft(sample, " \t\r\n")
+	start := byte('[')
+	if r.NDJSON {
+		start = '{'
+	}
+	return len(sample) > 0 && sample[0] == start && bytes.Contains(sample, []byte(`"amount"`))
+}
+
+// All returns an iterator over the transactions in source, which is either a
+// JSON array of transactions, or a sequence of transactions separated by
+// whitespace such as NDJSON. A transaction that is valid JSON but does not
+// follow the schema is yielded as a [*statement.RecordError].
+func (r *Reader) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		lines := &lineIndex{r: source}
+		buffered := bufio.NewReader(lines)
+
+		fail := func(err error) {
+			yield(domain.Transaction{}, fmt.Errorf("could not read JSON: %w", err))
+		}
+
+		// Skip the leading whitespace to see whether the input is an array.
+		var skipped int64
+		for {
+			b, err := buffered.ReadByte()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+			if !isSpace(b) {
+				if err := buffered.UnreadByte(); err != nil {
+					fail(err)
+					return
+				}
+				break
+			}
+			skipped++
+		}
+		start, _ := buffered.Peek(1)
+		array := start[0] == '['
+
+		decoder := json.NewDecoder(buffered)
+		if array {
+			if _, err := decoder.Token(); err != nil {
+				fail(err)
+				return
+			}
+		}
+
+		for decoder.More() {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				fail(err)
+				return
+			}
+			line := lines.lineAt(skipped + decoder.InputOffset() - int64(len(raw)))
+
+			var rec record
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				recErr := &statement.RecordError{File: r.fileName, Line: line, Value: string(raw), Err: err}
+				if !yield(domain.Transaction{}, recErr) {
+					return
+				}
+				continue
+			}
+			txn, member, err := rec.transaction()
+			if err != nil {
+				recErr := &statement.RecordError{File: r.fileName, Line: line, Column: member, Err: err}
+				if !yield(domain.Transaction{}, recErr) {
+					return
+				}
+				continue
+			}
+			if !yield(txn, nil) {
+				return
+			}
+		}
+
+		if array {
+			if _, err := decoder.Token(); err != nil {
+				fail(err)
+			}
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// lineIndex records the offsets of the line breaks read through it, so the
+// line of an offset can be found.
+type lineIndex struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64
+}
+
+func (l *lineIndex) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.newlines = append(l.newlines, l.offset+int64(i))
+		}
+	}
+	l.offset += int64(n)
+	return n, err
+}
+
+// lineAt returns the 1-based line number of offset.
+func (l *lineIndex) lineAt(offset int64) int {
+	return sort.Search(len(l.newlines), func(i int) bool { return l.newlines[i] >= offset }) + 1
+}
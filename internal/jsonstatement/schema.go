@@ -0,0 +1,145 @@
+// Package jsonstatement reads and writes transactions as JSON, either as one
+// JSON array or as newline-delimited JSON (NDJSON) with one transaction per
+// line. It is a lossless format for passing transactions between finCLI runs
+// and to other programs.
+//
+// # Schema
+//
+// Each transaction is a JSON object with these members:
+//
+//	version           Schema version, currently 1. Readers reject higher versions,
+//	                  and read a missing version as 1.
+//	id                Identifier given by the bank. Optional.
+//	end_to_end_id     Reference the payer gave the payment. Optional.
+//	date              Date of the transaction. Required.
+//	booking_date      Date the transaction was booked. Optional.
+//	value_date        Value date of the transaction. Optional.
+//	counterpart_name  Name of the payee or payer. Optional.
+//	description       Description or memo. Optional.
+//	category          Category, with ':' between subcategories. Optional.
+//	amount            Signed integer amount in minor units of the currency, such
+//	                  as cents; -1234 is -12.34 in a currency with two decimals.
+//	                  Required.
+//	currency          ISO 4217 currency code. Optional.
+//	account           Number or IBAN of the account of the statement. Optional.
+//	tags              Array of labels, such as ["vacation"]. Optional.
+//	notes             Free-form notes, apart from the description. Optional.
+//
+// Dates are ISO 8601: "2025-01-02" for dates without a time, and RFC 3339, as
+// in "2025-01-02T10:30:00+01:00", for dates with a time or time zone. The
+// number of decimals of amounts is the ISO 4217 minor unit of the currency,
+// or 2 if there is no currency.
+//
+// New optional members may be added without changing the version, so
+// readers must ignore members they do not know.
+package jsonstatement
+
+import (
+	"fincli/internal/domain"
+	"fmt"
+	"time"
+)
+
+// Version is the version of the schema written.
+const Version = 1
+
+// record is a transaction as described by the schema.
+type record struct {
+	Version         int      `json:"version"`
+	Id              string   `json:"id,omitempty"`
+	EndToEndId      string   `json:"end_to_end_id,omitempty"`
+	Date            string   `json:"date"`
+	BookingDate     string   `json:"booking_date,omitempty"`
+	ValueDate       string   `json:"value_date,omitempty"`
+	CounterpartName string   `json:"counterpart_name,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Category        string   `json:"category,omitempty"`
+	Amount          *int     `json:"amount"`
+	Currency        string   `json:"currency,omitempty"`
+	Account         string   `json:"account,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+}
+
+func newRecord(txn domain.Transaction) record {
+	return record{
+		Version:         Version,
+		Id:              txn.Id,
+		EndToEndId:      txn.EndToEndId,
+		Date:            formatDate(txn.Date),
+		BookingDate:     formatDate(txn.BookingDate),
+		ValueDate:       formatDate(txn.ValueDate),
+		CounterpartName: txn.CounterpartName,
+		Description:     txn.Description,
+		Category:        txn.Category,
+		Amount:          &txn.Amount,
+		Currency:        txn.Currency,
+		Account:         txn.Account,
+		Tags:            txn.Tags,
+		Notes:           txn.Notes,
+	}
+}
+
+// transaction returns the transaction of r, and the member that is invalid,
+// if any.
+func (r record) transaction() (domain.Transaction, string, error) {
+	txn := domain.Transaction{
+		Id:              r.Id,
+		EndToEndId:      r.EndToEndId,
+		CounterpartName: r.CounterpartName,
+		Description:     r.Description,
+		Category:        r.Category,
+		Currency:        r.Currency,
+		Account:         r.Account,
+		Tags:            r.Tags,
+		Notes:           r.Notes,
+	}
+	if r.Version > Version {
+		return txn, "version", fmt.Errorf("schema version %d is not supported, the latest is %d", r.Version, Version)
+	}
+	if r.Amount == nil {
+		return txn, "amount", fmt.Errorf("amount is missing")
+	}
+	txn.Amount = *r.Amount
+	if r.Date == "" {
+		return txn, "date", fmt.Errorf("date is missing")
+	}
+
+	var err error
+	if txn.Date, err = parseDate(r.Date); err != nil {
+		return txn, "date", err
+	}
+	if txn.BookingDate, err = parseDate(r.BookingDate); err != nil {
+		return txn, "booking_date", err
+	}
+	if txn.ValueDate, err = parseDate(r.ValueDate); err != nil {
+		return txn, "value_date", err
+	}
+	return txn, "", nil
+}
+
+// formatDate returns t as a date if it is midnight UTC, and as an RFC 3339
+// date and time otherwise. The zero time is returned as "".
+func formatDate(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)):
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,61 @@
+package jsonstatement
+
+import (
+	"bufio"
+	"encoding/json"
+	"fincli/internal/domain"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Writer writes transactions as a JSON array or as NDJSON. It implements
+// [statement.Writer].
+type Writer struct {
+	NDJSON bool
+}
+
+func NewWriter(ndjson bool) *Writer {
+	return &Writer{NDJSON: ndjson}
+}
+
+// WriteAll writes each transaction in txns as a JSON object on its own line,
+// and, unless the writer is for NDJSON, encloses them in an array.
+func (w *Writer) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	out := bufio.NewWriter(target)
+
+	first := true
+	for txn, err := range txns {
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(newRecord(txn))
+		if err != nil {
+			return fmt.Errorf("failed to write JSON: %v", err)
+		}
+
+		switch {
+		case w.NDJSON:
+		case first:
+			out.WriteString("[\n  ")
+		default:
+			out.WriteString(",\n  ")
+		}
+		out.Write(data)
+		if w.NDJSON {
+			out.WriteByte('\n')
+		}
+		first = false
+	}
+
+	if !w.NDJSON {
+		if first {
+			out.WriteString("[")
+		}
+		out.WriteString("\n]\n")
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write JSON: %v", err)
+	}
+	return nil
+}
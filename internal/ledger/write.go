@@ -0,0 +1,272 @@
+// Package ledger writes transactions as journals for the plain-text
+// accounting tools Ledger, hledger and Beancount.
+//
+// Each transaction is written as two postings: one to the asset account of
+// the statement, and one to an expense or income account that balances it.
+package ledger
+
+import (
+	"bufio"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Dialect is the journal syntax to write.
+type Dialect int
+
+const (
+	Ledger Dialect = iota
+	HLedger
+	Beancount
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case HLedger:
+		return "hledger"
+	case Beancount:
+		return "beancount"
+	}
+	return "ledger"
+}
+
+// Default account names.
+const (
+	DefaultAccount = "Assets:Bank"
+	UnknownExpense = "Expenses:Unknown"
+	UnknownIncome  = "Income:Unknown"
+)
+
+// rootAccounts are the top level accounts. A category that starts with one
+// of them is used as the balancing account as is.
+var rootAccounts = []string{"Assets:", "Liabilities:", "Equity:", "Income:", "Expenses:"}
+
+// Writer writes transactions as journal entries. It implements
+// [statement.Writer].
+type Writer struct {
+	Dialect Dialect
+	// Account is the asset account of the statement. It defaults to
+	// [DefaultAccount].
+	Account string
+	// Currency is the commodity of transactions without a currency. Beancount
+	// requires a commodity, so writing such transactions fails if it is
+	// empty.
+	Currency string
+}
+
+func NewWriter(dialect Dialect, account, currency string) *Writer {
+	return &Writer{Dialect: dialect, Account: account, Currency: currency}
+}
+
+// WriteAll writes each transaction in txns as a journal entry.
+//
+// The balancing account is the category of the transaction under Expenses or
+// Income, depending on the sign of the amount, or [UnknownExpense] and
+// [UnknownIncome] for transactions without a category.
+func (w *Writer) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	out := bufio.NewWriter(target)
+	first := true
+	for txn, err := range txns {
+		if err != nil {
+			return err
+		}
+		if !first {
+			fmt.Fprintln(out)
+		}
+		first = false
+		if err := w.writeEntry(out, txn); err != nil {
+			return fmt.Errorf("failed to write %s entry: %v", w.Dialect, err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s journal: %v", w.Dialect, err)
+	}
+	return nil
+}
+
+func (w *Writer) writeEntry(out io.Writer, txn domain.Transaction) error {
+	date := txn.Date
+	if date.IsZero() {
+		date = txn.BookingDate
+	}
+
+	currency := strings.ToUpper(txn.Currency)
+	if currency == "" {
+		currency = strings.ToUpper(w.Currency)
+	}
+	if currency == "" && w.Dialect == Beancount {
+		return fmt.Errorf("transaction on %s has no currency, and no default currency is set", date.Format(time.DateOnly))
+	}
+
+	account := w.Account
+	if account == "" {
+		account = DefaultAccount
+	}
+	amount := money.Format(txn.Amount, money.Exponent(currency), '.')
+	balance := money.Format(-txn.Amount, money.Exponent(currency), '.')
+	if currency != "" {
+		amount += " " + currency
+		balance += " " + currency
+	}
+
+	payee := oneLine(txn.CounterpartName)
+	memo := oneLine(txn.Description)
+
+	var metadata [][2]string
+	if w.Dialect == Beancount && txn.Id != "" {
+		metadata = append(metadata, [2]string{"id", txn.Id})
+	}
+	if !txn.BookingDate.IsZero() && !txn.BookingDate.Equal(date) {
+		metadata = append(metadata, [2]string{"booking_date", txn.BookingDate.Format(time.DateOnly)})
+	}
+	if !txn.ValueDate.IsZero() && !txn.ValueDate.Equal(date) {
+		metadata = append(metadata, [2]string{"value_date", txn.ValueDate.Format(time.DateOnly)})
+	}
+	if notes := oneLine(txn.Notes); notes != "" {
+		metadata = append(metadata, [2]string{"notes", notes})
+	}
+
+	var tags []string
+	for _, tag := range txn.Tags {
+		if tag = tagName(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	switch w.Dialect {
+	case Beancount:
+		fmt.Fprintf(out, "%s *", date.Format(time.DateOnly))
+		if payee != "" {
+			fmt.Fprintf(out, " %s", quote(payee))
+		}
+		fmt.Fprintf(out, " %s", quote(memo))
+		for _, tag := range tags {
+			fmt.Fprintf(out, " #%s", tag)
+		}
+		fmt.Fprintln(out)
+		for _, kv := range metadata {
+			value := kv[1]
+			if kv[0] == "id" || kv[0] == "notes" {
+				value = quote(value)
+			}
+			fmt.Fprintf(out, "  %s: %s\n", kv[0], value)
+		}
+		fmt.Fprintf(out, "  %s  %s\n", beancountAccount(account), amount)
+		fmt.Fprintf(out, "  %s  %s\n", beancountAccount(w.balancingAccount(txn)), balance)
+	default:
+		fmt.Fprintf(out, "%s *", date.Format(time.DateOnly))
+		if id := oneLine(txn.Id); id != "" {
+			fmt.Fprintf(out, " (%s)", strings.NewReplacer("(", "", ")", "").Replace(id))
+		}
+		description, comment := payee, memo
+		switch {
+		case payee == "":
+			// Without a payee, the memo describes the transaction.
+			description, comment = memo, ""
+		case w.Dialect == HLedger && memo != "":
+			// hledger reads "payee | note" as payee and note.
+			description, comment = strings.ReplaceAll(payee, "|", "/")+" | "+memo, ""
+		}
+		if description != "" {
+			fmt.Fprintf(out, " %s", description)
+		}
+		fmt.Fprintln(out)
+		if comment != "" {
+			fmt.Fprintf(out, "    ; %s\n", comment)
+		}
+		switch {
+		case len(tags) == 0:
+		case w.Dialect == HLedger:
+			fmt.Fprintf(out, "    ; %s:\n", strings.Join(tags, ":, "))
+		default:
+			fmt.Fprintf(out, "    ; :%s:\n", strings.Join(tags, ":"))
+		}
+		for _, kv := range metadata {
+			fmt.Fprintf(out, "    ; %s: %s\n", kv[0], kv[1])
+		}
+		fmt.Fprintf(out, "    %s  %s\n", ledgerAccount(account), amount)
+		fmt.Fprintf(out, "    %s  %s\n", ledgerAccount(w.balancingAccount(txn)), balance)
+	}
+	return nil
+}
+
+// balancingAccount returns the expense or income account of txn.
+func (w *Writer) balancingAccount(txn domain.Transaction) string {
+	category := strings.TrimSpace(txn.Category)
+	if category == "" {
+		if txn.Amount < 0 {
+			return UnknownExpense
+		}
+		return UnknownIncome
+	}
+	for _, root := range rootAccounts {
+		if strings.HasPrefix(category, root) {
+			return category
+		}
+	}
+	if txn.Amount < 0 {
+		return "Expenses:" + category
+	}
+	return "Income:" + category
+}
+
+// ledgerAccount returns name as a Ledger account name, in which two spaces
+// would end the name.
+func ledgerAccount(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// beancountAccount returns name as a Beancount account name, where each
+// component starts with an upper case letter or digit, and has only letters,
+// digits and dashes.
+func beancountAccount(name string) string {
+	components := strings.Split(name, ":")
+	for i, component := range components {
+		var b strings.Builder
+		for _, r := range component {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				r = '-'
+			}
+			if r == '-' && strings.HasSuffix(b.String(), "-") {
+				continue
+			}
+			b.WriteRune(r)
+		}
+		component = strings.Trim(b.String(), "-")
+		if component == "" {
+			component = "Unknown"
+		}
+		runes := []rune(component)
+		runes[0] = unicode.ToUpper(runes[0])
+		components[i] = string(runes)
+	}
+	return strings.Join(components, ":")
+}
+
+// oneLine returns s with line breaks and runs of spaces replaced by a single
+// space.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tagName returns tag with the characters that are not allowed in tags of
+// any of the dialects, such as spaces and ':', replaced with dashes.
+func tagName(tag string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_/.", r) {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(tag)), "-")
+}
+
+// quote returns s as a Beancount string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
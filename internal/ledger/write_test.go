@@ -0,0 +1,182 @@
+package ledger_test
+
+import (
+	"bytes"
+	"fincli/internal/domain"
+	"fincli/internal/ledger"
+	"fincli/internal/statement/statementtest"
+	"iter"
+	"testing"
+	"time"
+)
+
+func transactions() iter.Seq2[domain.Transaction, error] {
+	txns := []domain.Transaction{
+		{
+			Id:              "REF1",
+			Date:            time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+			ValueDate:       time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+			CounterpartName: `Kiwi "Oslo"`,
+			Description:     "Groceries",
+			Category:        "Food:Groceries",
+			Amount:          -11234,
+			Currency:        "NOK",
+			Tags:            []string{"weekly", "shared cost"},
+			Notes:           "Split with Kari",
+		},
+		{
+			Date:            time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
+			CounterpartName: "Employer",
+			Amount:          5000000,
+		},
+	}
+	return statementtest.Seq(txns)
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		dialect ledger.Dialect
+		want    string
+	}{
+		{
+			dialect: ledger.Ledger,
+			want: `2025-01-02 * (REF1) Kiwi "Oslo"
+    ; Groceries
+    ; :weekly:shared-cost:
+    ; value_date: 2025-01-03
+    ; notes: Split with Kari
+    Assets:Checking  -112.34 NOK
+    Expenses:Food:Groceries  112.34 NOK
+
+2025-01-15 * Employer
+    Assets:Checking  50000.00 EUR
+    Income:Unknown  -50000.00 EUR
+`,
+		},
+		{
+			dialect: ledger.HLedger,
+			want: `2025-01-02 * (REF1) Kiwi "Oslo" | Groceries
+    ; weekly:, shared-cost:
+    ; value_date: 2025-01-03
+    ; notes: Split with Kari
+    Assets:Checking  -112.34 NOK
+    Expenses:Food:Groceries  112.34 NOK
+
+2025-01-15 * Employer
+    Assets:Checking  50000.00 EUR
+    Income:Unknown  -50000.00 EUR
+`,
+		},
+		{
+			dialect: ledger.Beancount,
+			want: `2025-01-02 * "Kiwi \"Oslo\"" "Groceries" #weekly #shared-cost
+  id: "REF1"
+  value_date: 2025-01-03
+  notes: "Split with Kari"
+  Assets:Checking  -112.34 NOK
+  Expenses:Food:Groceries  112.34 NOK
+
+2025-01-15 * "Employer" ""
+  Assets:Checking  50000.00 EUR
+  Income:Unknown  -50000.00 EUR
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := ledger.NewWriter(tt.dialect, "Assets:Checking", "EUR")
+			if err := writer.WriteAll(&buf, transactions()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_BeancountWithoutCurrency(t *testing.T) {
+	var buf bytes.Buffer
+	err := ledger.NewWriter(ledger.Beancount, "", "").WriteAll(&buf, transactions())
+	if err == nil {
+		t.Fatal("expected error for transaction without currency")
+	}
+}
+
+func TestWriter_BeancountAccountNames(t *testing.T) {
+	txns := statementtest.Seq([]domain.Transaction{{
+		Date:     time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Category: "mat og drikke:café & bar",
+		Amount:   -100,
+		Currency: "NOK",
+	}})
+	var buf bytes.Buffer
+	if err := ledger.NewWriter(ledger.Beancount, "assets:my bank", "").WriteAll(&buf, txns); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `2025-01-02 * ""
+  Assets:My-bank  -1.00 NOK
+  Expenses:Mat-og-drikke:Café-bar  1.00 NOK
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriter_WithoutPayee(t *testing.T) {
+	txns := statementtest.Seq([]domain.Transaction{
+		{
+			Date:        time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+			Description: "VIPPS*KIWI",
+			Amount:      -100,
+			Currency:    "NOK",
+		},
+		{
+			Date:     time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+			Amount:   100,
+			Currency: "NOK",
+		},
+	})
+
+	tests := []struct {
+		dialect ledger.Dialect
+		want    string
+	}{
+		{
+			dialect: ledger.Ledger,
+			want: `2025-01-02 * VIPPS*KIWI
+    Assets:Bank  -1.00 NOK
+    Expenses:Unknown  1.00 NOK
+
+2025-01-03 *
+    Assets:Bank  1.00 NOK
+    Income:Unknown  -1.00 NOK
+`,
+		},
+		{
+			dialect: ledger.HLedger,
+			want: `2025-01-02 * VIPPS*KIWI
+    Assets:Bank  -1.00 NOK
+    Expenses:Unknown  1.00 NOK
+
+2025-01-03 *
+    Assets:Bank  1.00 NOK
+    Income:Unknown  -1.00 NOK
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ledger.NewWriter(tt.dialect, "", "").WriteAll(&buf, txns); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package money parses and formats decimal amounts as integers in the
+// smallest unit of a currency (e.g. cents), without rounding errors.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultExponent is the number of decimals used for currencies that are not
+// listed in ISO 4217 with a different minor unit, and when no currency is
+// known.
+const DefaultExponent = 2
+
+// exponents lists the ISO 4217 currencies with a minor unit other than
+// [DefaultExponent].
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Exponent returns the number of decimals of the minor unit of currency,
+// which is an ISO 4217 code such as "NOK". Unknown and empty codes get
+// [DefaultExponent].
+func Exponent(currency string) int {
+	if exp, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return DefaultExponent
+}
+
+// ErrSyntax is returned when a value is not a valid amount.
+var ErrSyntax = errors.New("invalid amount")
+
+// ErrPrecision is returned when a value has more significant decimals than
+// the minor unit of the currency can hold.
+var ErrPrecision = errors.New("amount has too many decimals")
+
+// Parser parses decimal amounts in a given notation.
+type Parser struct {
+	// DecimalSeparator separates the major and minor units. It defaults to
+	// '.' if not set.
+	DecimalSeparator rune
+	// ThousandsSeparator groups the digits of the major unit. Spaces are
+	// always accepted as thousands separator.
+	ThousandsSeparator rune
+	// Exponent is the number of decimals in the minor unit, see [Exponent].
+	Exponent int
+}
+
+// Parse returns value as an integer number of minor units.
+//
+// The value may have a leading or trailing '+' or '-' sign, or be enclosed
+// in parentheses to mark it as negative, as in "(12.34)". Missing decimals
+// are taken as zero, so with exponent 2 both "12,3" and "12" are parsed
+// exactly, as 1230 and 1200. Decimals beyond the exponent are only accepted
+// if they are zero.
+func (p Parser) Parse(value string) (int, error) {
+	decimalSep := p.DecimalSeparator
+	if decimalSep == 0 {
+		decimalSep = '.'
+	}
+
+	s, negative := trimSign(strings.TrimSpace(value))
+
+	var major, minor strings.Builder
+	seenSep := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			if seenSep {
+				minor.WriteRune(r)
+			} else {
+				major.WriteRune(r)
+			}
+		case r == decimalSep && !seenSep:
+			seenSep = true
+		case !seenSep && (r == p.ThousandsSeparator || unicode.IsSpace(r)):
+		default:
+			return 0, fmt.Errorf("%w: '%s'", ErrSyntax, value)
+		}
+	}
+	if major.Len() == 0 && minor.Len() == 0 {
+		return 0, fmt.Errorf("%w: '%s'", ErrSyntax, value)
+	}
+
+	decimals := minor.String()
+	if len(decimals) > p.Exponent {
+		if strings.Trim(decimals[p.Exponent:], "0") != "" {
+			return 0, fmt.Errorf("%w: '%s' has more than %d decimals", ErrPrecision, value, p.Exponent)
+		}
+		decimals = decimals[:p.Exponent]
+	}
+	decimals += strings.Repeat("0", p.Exponent-len(decimals))
+
+	amount, err := strconv.Atoi(major.String() + decimals)
+	if err != nil {
+		return 0, fmt.Errorf("%w: '%s': %v", ErrSyntax, value, err)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// trimSign removes the sign notation from s, and reports whether it marks s
+// as negative.
+func trimSign(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		return strings.TrimSpace(s[1 : len(s)-1]), true
+	}
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		return strings.TrimSpace(s[1:]), s[0] == '-'
+	}
+	if s != "" && (s[len(s)-1] == '-' || s[len(s)-1] == '+') {
+		return strings.TrimSpace(s[:len(s)-1]), s[len(s)-1] == '-'
+	}
+	return s, false
+}
+
+// Format returns amount, in minor units, as a decimal number with exponent
+// decimals separated by decimalSep. Negative amounts get a leading '-'.
+func Format(amount int, exponent int, decimalSep rune) string {
+	if decimalSep == 0 {
+		decimalSep = '.'
+	}
+
+	sign := ""
+	digits := strconv.Itoa(amount)
+	if amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if exponent <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + string(decimalSep) + digits[split:]
+}
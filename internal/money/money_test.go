@@ -0,0 +1,120 @@
+package money_test
+
+import (
+	"errors"
+	"fincli/internal/money"
+	"testing"
+)
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  money.Parser
+		value   string
+		want    int
+		wantErr error
+	}{
+		{"comma decimal", money.Parser{DecimalSeparator: ',', Exponent: 2}, "12,34", 1234, nil},
+		{"one decimal", money.Parser{DecimalSeparator: ',', Exponent: 2}, "12,3", 1230, nil},
+		{"no decimals", money.Parser{DecimalSeparator: ',', Exponent: 2}, "12", 1200, nil},
+		{"leading zero", money.Parser{DecimalSeparator: ',', Exponent: 2}, "0,5", 50, nil},
+		{"no major", money.Parser{DecimalSeparator: '.', Exponent: 2}, ".5", 50, nil},
+		{"thousands", money.Parser{DecimalSeparator: ',', ThousandsSeparator: '.', Exponent: 2}, "1.234,56", 123456, nil},
+		{"space thousands", money.Parser{DecimalSeparator: ',', Exponent: 2}, "1 234 567,89", 123456789, nil},
+		{"nbsp thousands", money.Parser{DecimalSeparator: ',', Exponent: 2}, "1\u00a0234,00", 123400, nil},
+		{"negative", money.Parser{DecimalSeparator: '.', Exponent: 2}, "-12.34", -1234, nil},
+		{"positive sign", money.Parser{DecimalSeparator: '.', Exponent: 2}, "+ 12.34", 1234, nil},
+		{"parentheses", money.Parser{DecimalSeparator: '.', ThousandsSeparator: ',', Exponent: 2}, "(1,234.50)", -123450, nil},
+		{"trailing minus", money.Parser{DecimalSeparator: ',', Exponent: 2}, "12,34-", -1234, nil},
+		{"trailing plus", money.Parser{DecimalSeparator: ',', Exponent: 2}, "12,34+", 1234, nil},
+		{"two signs", money.Parser{DecimalSeparator: ',', Exponent: 2}, "-12,34-", 0, money.ErrSyntax},
+		{"unbalanced parenthesis", money.Parser{DecimalSeparator: '.', Exponent: 2}, "(12.34", 0, money.ErrSyntax},
+		{"default separator", money.Parser{Exponent: 2}, "1.50", 150, nil},
+		{"JPY", money.Parser{DecimalSeparator: '.', ThousandsSeparator: ',', Exponent: 0}, "1,234", 1234, nil},
+		{"BHD", money.Parser{DecimalSeparator: '.', Exponent: 3}, "1.5", 1500, nil},
+		{"trailing zero decimals", money.Parser{DecimalSeparator: '.', Exponent: 2}, "1.500", 150, nil},
+		{"too many decimals", money.Parser{DecimalSeparator: '.', Exponent: 2}, "1.505", 0, money.ErrPrecision},
+		{"unknown separator", money.Parser{DecimalSeparator: ',', Exponent: 2}, "1.234,56", 0, money.ErrSyntax},
+		{"two decimal separators", money.Parser{DecimalSeparator: ',', Exponent: 2}, "1,2,3", 0, money.ErrSyntax},
+		{"thousands after decimal", money.Parser{DecimalSeparator: ',', ThousandsSeparator: '.', Exponent: 2}, "1,23.4", 0, money.ErrSyntax},
+		{"empty", money.Parser{Exponent: 2}, "", 0, money.ErrSyntax},
+		{"sign only", money.Parser{Exponent: 2}, "-", 0, money.ErrSyntax},
+		{"letters", money.Parser{Exponent: 2}, "12abc", 0, money.ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Parse(tt.value)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount   int
+		exponent int
+		sep      rune
+		want     string
+	}{
+		{1234, 2, '.', "12.34"},
+		{-1234, 2, ',', "-12,34"},
+		{-5, 2, '.', "-0.05"},
+		{50, 2, '.', "0.50"},
+		{0, 2, '.', "0.00"},
+		{1234, 0, '.', "1234"},
+		{-1500, 3, '.', "-1.500"},
+		{7, 3, ',', "0,007"},
+		{150, 2, 0, "1.50"},
+	}
+
+	for _, tt := range tests {
+		got := money.Format(tt.amount, tt.exponent, tt.sep)
+		if got != tt.want {
+			t.Errorf("Format(%d, %d, %q) = %q, want %q", tt.amount, tt.exponent, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, currency := range []string{"NOK", "JPY", "BHD"} {
+		exp := money.Exponent(currency)
+		parser := money.Parser{DecimalSeparator: ',', Exponent: exp}
+		for _, amount := range []int{0, 1, -1, 99, -100, 123456789, -987654321} {
+			got, err := parser.Parse(money.Format(amount, exp, ','))
+			if err != nil {
+				t.Fatalf("%s %d: unexpected error: %v", currency, amount, err)
+			}
+			if got != amount {
+				t.Errorf("%s: round trip of %d gave %d", currency, amount, got)
+			}
+		}
+	}
+}
+
+func TestExponent(t *testing.T) {
+	for currency, want := range map[string]int{"NOK": 2, "eur": 2, "JPY": 0, "BHD": 3, "": 2} {
+		if got := money.Exponent(currency); got != want {
+			t.Errorf("Exponent(%q) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	for code, want := range map[string]bool{"NOK": true, "eur": true, "JPY": true, "XYZ": false, "": false, "NOKK": false} {
+		if got := money.ValidCurrency(code); got != want {
+			t.Errorf("ValidCurrency(%q) = %t, want %t", code, got, want)
+		}
+	}
+}
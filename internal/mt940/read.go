@@ -0,0 +1,313 @@
+// Package mt940 reads bank statements in the SWIFT MT940 customer statement
+// format.
+//
+// An MT940 statement is a list of tagged fields: the statement reference
+// (:20:), the account (:25:), the opening balance (:60F:), one statement line
+// (:61:) per transaction, each optionally followed by a narrative (:86:), and
+// the closing balance (:62F:). A file may hold several statements, each
+// ended by a '-' line, and may be wrapped in SWIFT message blocks.
+package mt940
+
+import (
+	"bufio"
+	"bytes"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reader reads the statement lines of MT940 statements as transactions. It
+// implements [statement.Reader], [statement.Detector] and
+// [statement.Summarizer].
+type Reader struct {
+	fileName  string
+	summaries []statement.Summary
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (r *Reader) SetFileName(name string) {
+	r.fileName = name
+}
+
+var detectPattern = regexp.MustCompile(`(?m)^:20:.*\r?\n(?:.*\r?\n)*?:25:`)
+
+// Detect reports whether sample starts with the fields of an MT940 statement.
+func (r *Reader) Detect(sample []byte) bool {
+	return detectPattern.Match(sample) &&
+		(bytes.Contains(sample, []byte(":60F:")) || bytes.Contains(sample, []byte(":60M:")))
+}
+
+// Summaries returns the statement reference (:20:), account (:25:) and the
+// opening and closing balances of each statement read by All.
+func (r *Reader) Summaries() []statement.Summary {
+	return r.summaries
+}
+
+// field is a tagged field, with its continuation lines joined by '\n'.
+type field struct {
+	tag   string
+	value string
+	line  int
+}
+
+// All returns an iterator over the transactions in source. The narrative of
+// a statement line is used as description, and the counterpart name is taken
+// from it when it is structured with ?-subfields.
+func (r *Reader) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		r.summaries = nil
+
+		var (
+			summary *statement.Summary
+			pending *field // The :61: field whose :86: may follow.
+			details string
+		)
+
+		// flush yields the pending statement line, if any.
+		flush := func() bool {
+			if pending == nil {
+				return true
+			}
+			var currency, account string
+			if summary != nil {
+				currency, account = summary.Currency, summary.Account
+			}
+			txn, recErr := parseStatementLine(pending.value, details, currency)
+			txn.Account = account
+			line := pending.line
+			pending, details = nil, ""
+			if recErr != nil {
+				recErr.File, recErr.Line = r.fileName, line
+				return yield(domain.Transaction{}, recErr)
+			}
+			return yield(txn, nil)
+		}
+
+		handle := func(f field) bool {
+			if f.tag != "86" && !flush() {
+				return false
+			}
+			if f.tag != "20" && summary == nil {
+				// Fields before the first :20:, which should not happen
+				// in a valid file, are read as part of a statement.
+				r.summaries = append(r.summaries, statement.Summary{})
+				summary = &r.summaries[len(r.summaries)-1]
+			}
+
+			var err error
+			switch f.tag {
+			case "20":
+				r.summaries = append(r.summaries, statement.Summary{Id: strings.TrimSpace(f.value)})
+				summary = &r.summaries[len(r.summaries)-1]
+			case "25":
+				summary.Account = strings.TrimSpace(f.value)
+			case "60F", "60M":
+				if summary.Opening == nil {
+					summary.Opening, err = parseBalance(f.value)
+					if summary.Opening != nil {
+						summary.Currency = summary.Opening.Currency
+					}
+				}
+			case "62F", "62M":
+				summary.Closing, err = parseBalance(f.value)
+			case "61":
+				pending = &f
+			case "86":
+				if pending != nil {
+					details = f.value
+				}
+			}
+			if err != nil {
+				recErr := &statement.RecordError{File: r.fileName, Line: f.line, Column: ":" + f.tag + ":", Value: f.value, Err: err}
+				return yield(domain.Transaction{}, recErr)
+			}
+			return true
+		}
+
+		scanner := bufio.NewScanner(source)
+		var current *field
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := strings.TrimRight(scanner.Text(), " \r")
+
+			tag, value, isTag := cutTag(line)
+			switch {
+			case isTag:
+				if current != nil && !handle(*current) {
+					return
+				}
+				current = &field{tag: tag, value: value, line: lineNum}
+			case line == "-" || line == "-}" || strings.HasPrefix(line, "{"):
+				// The end of a statement, or a SWIFT block header.
+				if current != nil && !handle(*current) {
+					return
+				}
+				current = nil
+				if !flush() {
+					return
+				}
+			case current != nil:
+				current.value += "\n" + line
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(domain.Transaction{}, fmt.Errorf("could not read MT940: %w", err))
+			return
+		}
+		if current != nil && !handle(*current) {
+			return
+		}
+		flush()
+	}
+}
+
+var tagPattern = regexp.MustCompile(`^:(\d\d[A-Z]?):`)
+
+// cutTag splits a line that starts a field into its tag and value.
+func cutTag(line string) (tag, value string, ok bool) {
+	m := tagPattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], line[len(m[0]):], true
+}
+
+var balancePattern = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+
+// parseBalance parses a balance field such as "C250131NOK1387,66".
+func parseBalance(value string) (*statement.Balance, error) {
+	m := balancePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return nil, fmt.Errorf("invalid balance '%s'", value)
+	}
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse date '%s'", m[2])
+	}
+	amount, err := parseAmount(m[4], m[3])
+	if err != nil {
+		return nil, err
+	}
+	if m[1] == "D" {
+		amount = -amount
+	}
+	return &statement.Balance{Date: date, Amount: amount, Currency: m[3]}, nil
+}
+
+// statementLinePattern matches the first line of a :61: field: value date,
+// optional entry date, debit/credit mark, optional funds code, amount,
+// transaction type, reference for the account owner and optional reference
+// of the bank.
+var statementLinePattern = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+
+func parseStatementLine(value, details, currency string) (domain.Transaction, *statement.RecordError) {
+	first, supplementary, _ := strings.Cut(value, "\n")
+	m := statementLinePattern.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return domain.Transaction{}, &statement.RecordError{Column: ":61:", Value: first, Err: fmt.Errorf("invalid statement line")}
+	}
+
+	txn := domain.Transaction{Currency: currency}
+
+	var err error
+	if txn.ValueDate, err = time.Parse("060102", m[1]); err != nil {
+		return txn, &statement.RecordError{Column: ":61:", Value: m[1], Err: fmt.Errorf("could not parse value date '%s'", m[1])}
+	}
+	txn.Date = txn.ValueDate
+	if m[2] != "" {
+		if txn.BookingDate, err = entryDate(m[2], txn.ValueDate); err != nil {
+			return txn, &statement.RecordError{Column: ":61:", Value: m[2], Err: err}
+		}
+		txn.Date = txn.BookingDate
+	}
+
+	if txn.Amount, err = parseAmount(m[5], currency); err != nil {
+		return txn, &statement.RecordError{Column: ":61:", Value: m[5], Err: err}
+	}
+	// Reversal of a credit (RC) is a debit, and the other way around.
+	if m[3] == "D" || m[3] == "RC" {
+		txn.Amount = -txn.Amount
+	}
+
+	customerRef := strings.TrimSpace(m[7])
+	if customerRef == "NONREF" {
+		customerRef = ""
+	}
+	txn.Id = strings.TrimSpace(m[8])
+	if txn.Id == "" {
+		txn.Id = customerRef
+	}
+
+	txn.CounterpartName, txn.Description = parseNarrative(details)
+	if txn.Description == "" {
+		txn.Description = strings.TrimSpace(supplementary)
+	}
+	return txn, nil
+}
+
+// entryDate returns the date of an entry date such as "0102", in the year of
+// the value date, or in the year before or after when the dates are on each
+// side of a new year.
+func entryDate(value string, valueDate time.Time) (time.Time, error) {
+	date, err := time.Parse("0102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse entry date '%s'", value)
+	}
+	year := valueDate.Year()
+	switch {
+	case date.Month() == time.December && valueDate.Month() == time.January:
+		year--
+	case date.Month() == time.January && valueDate.Month() == time.December:
+		year++
+	}
+	return time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseAmount parses an MT940 amount, which has ',' as decimal separator.
+func parseAmount(value, currency string) (int, error) {
+	parser := money.Parser{DecimalSeparator: ',', Exponent: money.Exponent(currency)}
+	return parser.Parse(value)
+}
+
+var subfieldPattern = regexp.MustCompile(`\?(\d\d)`)
+
+// parseNarrative returns the counterpart name and the description in the
+// narrative of a :86: field. Structured narratives, as used by German banks,
+// have subfields such as "?20" to "?29" for the purpose and "?32" and "?33"
+// for the name of the counterpart. Other narratives are used as description
+// as a whole.
+func parseNarrative(details string) (name, description string) {
+	text := strings.ReplaceAll(details, "\n", "")
+	locs := subfieldPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return "", strings.Join(strings.Fields(details), " ")
+	}
+
+	var purpose, names []string
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		code, value := text[loc[2]:loc[3]], strings.TrimSpace(text[loc[1]:end])
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			purpose = append(purpose, value)
+		case code == "32" || code == "33":
+			names = append(names, value)
+		}
+	}
+	return strings.Join(names, ""), strings.Join(strings.Fields(strings.Join(purpose, " ")), " ")
+}
@@ -0,0 +1,112 @@
+package mt940_test
+
+import (
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/mt940"
+	"fincli/internal/statement"
+	"fincli/internal/statement/statementtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const statements = `{1:F01BANKNOKKAXXX0000000000}{2:I940BANKNOKKXXXXN}{4:
+:20:STMT001
+:25:NO9386011117947
+:28C:1/1
+:60F:C241231NOK1000,00
+:61:2412311231D112,34NTRFNONREF//BREF1
+:86:Kiwi Oslo
+card purchase
+:61:2501020102C500,NMSCINV-42
+Salary January
+:62F:C250102NOK1387,66
+-}
+:20:STMT002
+:25:DE89370400440532013000
+:60F:C250101EUR0,00
+:61:250103RC1,50NCHGNONREF
+:86:166?00GUTSCHRIFT?20Invoice 123?21 paid?32Max Muster?33mann
+:62M:D250103EUR1,50
+-
+`
+
+func TestReader(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	want := []domain.Transaction{
+		{
+			Id: "BREF1", Date: date(2024, 12, 31), BookingDate: date(2024, 12, 31), ValueDate: date(2024, 12, 31),
+			CounterpartName: "", Description: "Kiwi Oslo card purchase", Amount: -11234, Currency: "NOK", Account: "NO9386011117947",
+		},
+		{
+			Id: "INV-42", Date: date(2025, 1, 2), BookingDate: date(2025, 1, 2), ValueDate: date(2025, 1, 2),
+			Description: "Salary January", Amount: 50000, Currency: "NOK", Account: "NO9386011117947",
+		},
+		{
+			Date: date(2025, 1, 3), ValueDate: date(2025, 1, 3),
+			CounterpartName: "Max Mustermann", Description: "Invoice 123 paid", Amount: -150, Currency: "EUR", Account: "DE89370400440532013000",
+		},
+	}
+
+	reader := mt940.NewReader()
+	if !reader.Detect([]byte(statements)) {
+		t.Error("expected MT940 to be detected")
+	}
+
+	got := statementtest.Collect(t, reader.All(strings.NewReader(statements)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n\t%+v\nwant:\n\t%+v", got, want)
+	}
+
+	wantSummaries := []statement.Summary{
+		{
+			Id: "STMT001", Account: "NO9386011117947", Currency: "NOK",
+			Opening: &statement.Balance{Date: date(2024, 12, 31), Amount: 100000, Currency: "NOK"},
+			Closing: &statement.Balance{Date: date(2025, 1, 2), Amount: 138766, Currency: "NOK"},
+		},
+		{
+			Id: "STMT002", Account: "DE89370400440532013000", Currency: "EUR",
+			Opening: &statement.Balance{Date: date(2025, 1, 1), Amount: 0, Currency: "EUR"},
+			Closing: &statement.Balance{Date: date(2025, 1, 3), Amount: -150, Currency: "EUR"},
+		},
+	}
+	if summaries := reader.Summaries(); !reflect.DeepEqual(summaries, wantSummaries) {
+		t.Errorf("summaries:\ngot:\t%+v\nwant:\t%+v", summaries, wantSummaries)
+	}
+}
+
+func TestReader_RecordError(t *testing.T) {
+	data := ":20:X\n:25:123\n:60F:C250101NOK0,00\n:61:250102D1.00NTRFNONREF\n:61:250103C2,00NTRFNONREF\n:62F:C250103NOK1,00\n-\n"
+
+	reader := mt940.NewReader()
+	reader.SetFileName("stmt.sta")
+	var (
+		txns   []domain.Transaction
+		recErr *statement.RecordError
+	)
+	for txn, err := range reader.All(strings.NewReader(data)) {
+		if err != nil {
+			if !errors.As(err, &recErr) {
+				t.Fatalf("expected *statement.RecordError, got %v", err)
+			}
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	if recErr == nil || recErr.File != "stmt.sta" || recErr.Line != 4 || recErr.Column != ":61:" {
+		t.Errorf("unexpected error: %v", recErr)
+	}
+	if len(txns) != 1 || txns[0].Amount != 200 {
+		t.Errorf("expected reading to continue after the error, got %+v", txns)
+	}
+}
+
+func TestReader_Detect(t *testing.T) {
+	if mt940.NewReader().Detect([]byte("Date,Payee\n:20:,x\n")) {
+		t.Error("expected CSV not to be detected")
+	}
+}
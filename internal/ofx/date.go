@@ -0,0 +1,56 @@
+package ofx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDate parses an OFX date, which is YYYYMMDD optionally followed by
+// HHMMSS, milliseconds and a time zone offset in hours, as in
+// "20240131120000.000[-5:EST]". Dates without an offset are in UTC.
+func parseDate(value string) (time.Time, error) {
+	s, zone, hasZone := strings.Cut(value, "[")
+	s = strings.TrimSpace(s)
+
+	layout := ""
+	switch {
+	case len(s) == 8:
+		layout = "20060102"
+	case len(s) == 12:
+		layout = "200601021504"
+	case len(s) == 14:
+		layout = "20060102150405"
+	case len(s) > 15 && s[14] == '.':
+		layout = "20060102150405." + strings.Repeat("0", len(s)-15)
+	default:
+		return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+	}
+
+	loc := time.UTC
+	if hasZone {
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse time zone of date '%s'", value)
+		}
+		if name == "" {
+			name = "UTC" + offset
+		}
+		loc = time.FixedZone(name, int(hours*3600))
+	}
+
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+	}
+	return t, nil
+}
+
+// formatDate formats t as an OFX date with time zone offset.
+func formatDate(t time.Time) string {
+	_, offset := t.Zone()
+	name := t.Format("MST")
+	return fmt.Sprintf("%s[%s:%s]", t.Format("20060102150405"), strconv.FormatFloat(float64(offset)/3600, 'f', -1, 64), name)
+}
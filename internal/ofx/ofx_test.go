@@ -0,0 +1,311 @@
+package ofx_test
+
+import (
+	"bytes"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/ofx"
+	"fincli/internal/statement"
+	"fincli/internal/statement/statementtest"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20250105</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>NOK
+<BANKACCTFROM><BANKID>1234<ACCTID>5678<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20250101<DTEND>20250105
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250102120000[+1:CET]
+<DTUSER>20250101
+<TRNAMT>-12.34
+<FITID>A1
+<NAME>Store &amp; Co
+<MEMO>Groceries
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250104
+<TRNAMT>500
+<FITID>A2
+<NAME>Employer
+<CURRENCY><CURRATE>1.0<CURSYM>EUR</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>487.66<DTASOF>20250105</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestReader_SGML(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	want := []domain.Transaction{
+		{
+			Id:              "A1",
+			Date:            time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			BookingDate:     time.Date(2025, time.January, 2, 12, 0, 0, 0, cet),
+			CounterpartName: "Store & Co",
+			Description:     "Groceries",
+			Amount:          -1234,
+			Currency:        "NOK",
+			Account:         "5678",
+		},
+		{
+			Id:              "A2",
+			Date:            time.Date(2025, time.January, 4, 0, 0, 0, 0, time.UTC),
+			BookingDate:     time.Date(2025, time.January, 4, 0, 0, 0, 0, time.UTC),
+			CounterpartName: "Employer",
+			Amount:          50000,
+			Currency:        "EUR",
+			Account:         "5678",
+		},
+	}
+
+	got := statementtest.Collect(t, ofx.NewReader().All(strings.NewReader(sgmlStatement)))
+	if len(got) != len(want) {
+		t.Fatalf("expected %d transactions, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !equal(got[i], want[i]) {
+			t.Errorf("transaction %d:\ngot:\t%+v\nwant:\t%+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReader_XML(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <CURDEF>USD</CURDEF>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20250102</DTPOSTED>
+        <TRNAMT>-1.50</TRNAMT>
+        <FITID>X</FITID>
+        <NAME>Coffee</NAME>
+        <MEMO/>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+	got := statementtest.Collect(t, ofx.NewReader().All(strings.NewReader(data)))
+	if len(got) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(got))
+	}
+	want := domain.Transaction{
+		Id:              "X",
+		Date:            time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		BookingDate:     time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		CounterpartName: "Coffee",
+		Amount:          -150,
+		Currency:        "USD",
+	}
+	if !equal(got[0], want) {
+		t.Errorf("got:\t%+v\nwant:\t%+v", got[0], want)
+	}
+}
+
+func TestReader_OrigCurrency(t *testing.T) {
+	data := `<OFX>
+<CURDEF>NOK
+<STMTTRN>
+<DTPOSTED>20250102
+<TRNAMT>-115.00
+<FITID>F1
+<NAME>Hotel
+<ORIGCURRENCY><CURRATE>11.5<CURSYM>EUR</ORIGCURRENCY>
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>20250103
+<TRNAMT>-1500
+<FITID>F2
+<NAME>Ramen
+<CURRENCY><CURRATE>0.07<CURSYM>JPY</CURRENCY>
+</STMTTRN>
+</OFX>`
+	got := statementtest.Collect(t, ofx.NewReader().All(strings.NewReader(data)))
+	if len(got) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(got))
+	}
+	if got[0].Amount != -11500 || got[0].Currency != "NOK" {
+		t.Errorf("expected ORIGCURRENCY to be ignored, got %d %s", got[0].Amount, got[0].Currency)
+	}
+	if got[1].Amount != -1500 || got[1].Currency != "JPY" {
+		t.Errorf("expected CURRENCY to set the currency, got %d %s", got[1].Amount, got[1].Currency)
+	}
+}
+
+func TestReader_RecordError(t *testing.T) {
+	data := "<OFX>\n<STMTTRN>\n<DTPOSTED>yesterday\n<TRNAMT>1\n</STMTTRN>\n<STMTTRN>\n<DTPOSTED>20250101\n<TRNAMT>2\n</STMTTRN>\n</OFX>"
+
+	reader := ofx.NewReader()
+	reader.SetFileName("bank.ofx")
+	var (
+		txns   []domain.Transaction
+		recErr *statement.RecordError
+	)
+	for txn, err := range reader.All(strings.NewReader(data)) {
+		if err != nil {
+			if !errors.As(err, &recErr) {
+				t.Fatalf("expected *statement.RecordError, got %v", err)
+			}
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	if recErr == nil {
+		t.Fatal("expected an error for the invalid date")
+	}
+	if recErr.File != "bank.ofx" || recErr.Line != 2 || recErr.Column != "DTPOSTED" {
+		t.Errorf("unexpected error location: %v", recErr)
+	}
+	if len(txns) != 1 || txns[0].Amount != 200 {
+		t.Errorf("expected reading to continue after the error, got %+v", txns)
+	}
+}
+
+func TestReader_Detect(t *testing.T) {
+	reader := ofx.NewReader()
+	if !reader.Detect([]byte(sgmlStatement)) {
+		t.Error("expected SGML statement to be detected")
+	}
+	if !reader.Detect([]byte("<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\"?>\n<OFX>")) {
+		t.Error("expected XML statement to be detected")
+	}
+	if reader.Detect([]byte("Date,Payee,Amount\n2025-01-01,Store,1.00\n")) {
+		t.Error("expected CSV not to be detected")
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	txns := statementtest.Collect(t, ofx.NewReader().All(strings.NewReader(sgmlStatement)))
+	noId := domain.Transaction{
+		Date:            time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC),
+		CounterpartName: "A payee with a name much longer than thirty-two characters",
+		Amount:          -100,
+		Currency:        "NOK",
+	}
+	txns = append(txns, noId, noId)
+
+	var buf bytes.Buffer
+	writer := ofx.NewWriter()
+	writer.BankId = "1234"
+	if err := writer.WriteAll(&buf, statementtest.Seq(txns)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, s := range []string{"<CURDEF>NOK</CURDEF>", "<NAME>Store &amp; Co</NAME>", "<TRNTYPE>DEBIT</TRNTYPE>", "<CURSYM>EUR</CURSYM>", "<DTSTART>20250102",
+		"<BANKID>1234</BANKID>", "<ACCTID>5678</ACCTID>"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q:\n%s", s, out)
+		}
+	}
+
+	got := statementtest.Collect(t, ofx.NewReader().All(strings.NewReader(out)))
+	if len(got) != len(txns) {
+		t.Fatalf("expected %d transactions, got %d", len(txns), len(got))
+	}
+	for i := range 2 {
+		if !equal(got[i], txns[i]) {
+			t.Errorf("transaction %d:\ngot:\t%+v\nwant:\t%+v", i, got[i], txns[i])
+		}
+	}
+	for _, s := range []string{"CURRATE"} {
+		if strings.Contains(out, s) {
+			t.Errorf("expected output not to contain %s:\n%s", s, out)
+		}
+	}
+	if strings.Index(out, "<CURRENCY>") < strings.Index(out, "<NAME>Employer") {
+		t.Errorf("expected CURRENCY after NAME and MEMO:\n%s", out)
+	}
+	if got[2].Id == "" || got[2].Id == got[3].Id {
+		t.Errorf("expected unique generated FITIDs, got %q and %q", got[2].Id, got[3].Id)
+	}
+	if n := len([]rune(got[2].CounterpartName)); n > 32 {
+		t.Errorf("expected NAME to be truncated to at most 32 characters, got %d", n)
+	}
+}
+
+func TestWriter_Balance(t *testing.T) {
+	txns := []domain.Transaction{
+		{Date: time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), Amount: -1234, Currency: "NOK"},
+		{Date: time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC), Amount: 50000, Currency: "NOK"},
+		{Date: time.Date(2025, time.January, 4, 0, 0, 0, 0, time.UTC), Amount: 100, Currency: "EUR"},
+	}
+
+	tests := []struct {
+		name      string
+		summaries []statement.Summary
+		want      string
+	}{
+		{
+			name: "closing balance",
+			summaries: []statement.Summary{{
+				Account: "5678", Currency: "NOK",
+				Closing: &statement.Balance{Date: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), Amount: 123456},
+			}},
+			want: "<BALAMT>1234.56</BALAMT>",
+		},
+		{
+			name: "sum of transactions",
+			want: "<BALAMT>487.66</BALAMT>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := ofx.NewWriter()
+			writer.SetAccount("1234", "5678")
+			writer.SetSummaries(func() []statement.Summary { return tt.summaries })
+			var buf bytes.Buffer
+			if err := writer.WriteAll(&buf, statementtest.Seq(txns)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("expected output to contain %q:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriter_UnknownAccount(t *testing.T) {
+	txns := []domain.Transaction{{Date: time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), Amount: -1234}}
+
+	writer := ofx.NewWriter()
+	writer.BankId = "1234"
+	if err := writer.WriteAll(io.Discard, statementtest.Seq(txns)); err == nil {
+		t.Error("expected error for statement without account")
+	}
+
+	writer = ofx.NewWriter()
+	writer.AccountId = "5678"
+	if err := writer.WriteAll(io.Discard, statementtest.Seq(txns)); err == nil {
+		t.Error("expected error for statement without bank")
+	}
+}
+
+func equal(a, b domain.Transaction) bool {
+	return a.Id == b.Id && a.Date.Equal(b.Date) && a.BookingDate.Equal(b.BookingDate) &&
+		a.ValueDate.Equal(b.ValueDate) && a.CounterpartName == b.CounterpartName &&
+		a.Description == b.Description && a.Amount == b.Amount && a.Currency == b.Currency &&
+		a.Account == b.Account
+}
@@ -0,0 +1,273 @@
+// Package ofx reads and writes bank statements in the Open Financial
+// Exchange format, both the SGML based OFX 1.x and the XML based OFX 2.x.
+// QFX files are OFX files with extra Intuit elements, and are read the same
+// way.
+package ofx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// Reader reads the transactions of OFX statements. It implements
+// [statement.Reader] and [statement.Detector].
+type Reader struct {
+	fileName string
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (r *Reader) SetFileName(name string) {
+	r.fileName = name
+}
+
+// Detect reports whether sample looks like the start of an OFX file.
+func (r *Reader) Detect(sample []byte) bool {
+	head := bytes.ToUpper(sample[:min(len(sample), 1024)])
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+// All returns an iterator over the transactions (STMTTRN) in source.
+//
+// The currency of a transaction is taken from its CURRENCY aggregate, or else
+// from the CURDEF of the statement. An ORIGCURRENCY aggregate is ignored, as
+// the amount has already been converted to CURDEF. DTUSER is used as the
+// transaction date when present, and DTPOSTED otherwise.
+func (r *Reader) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		tokens := newTokenizer(source)
+
+		var (
+			defaultCurrency string
+			account         string
+			txn             *transaction
+			aggregate       string // CURRENCY or ORIGCURRENCY inside a transaction
+			leaf            string // The element whose value is expected next
+		)
+		for {
+			tok, err := tokens.next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(domain.Transaction{}, fmt.Errorf("could not read OFX: %w", err))
+				return
+			}
+
+			switch tok.kind {
+			case tokenStart:
+				leaf = tok.value
+				switch tok.value {
+				case "STMTTRN":
+					txn = &transaction{line: tok.line, fields: map[string]string{}}
+				case "CURRENCY", "ORIGCURRENCY":
+					aggregate = tok.value
+				}
+			case tokenEnd:
+				leaf = ""
+				switch tok.value {
+				case "STMTTRN":
+					if txn == nil {
+						continue
+					}
+					result, recErr := txn.parse(defaultCurrency)
+					txn = nil
+					if recErr != nil {
+						recErr.File = r.fileName
+						if !yield(domain.Transaction{}, recErr) {
+							return
+						}
+						continue
+					}
+					result.Account = account
+					if !yield(result, nil) {
+						return
+					}
+				case "CURRENCY", "ORIGCURRENCY":
+					aggregate = ""
+				}
+			case tokenText:
+				switch {
+				case leaf == "":
+				case txn != nil && aggregate == "CURRENCY" && leaf == "CURSYM":
+					txn.fields["CURSYM"] = tok.value
+				case txn != nil && aggregate == "":
+					txn.fields[leaf] = tok.value
+				case txn == nil && leaf == "CURDEF":
+					defaultCurrency = tok.value
+				case txn == nil && leaf == "ACCTID":
+					account = tok.value
+				}
+				leaf = ""
+			}
+		}
+	}
+}
+
+// transaction holds the values of the elements of a STMTTRN aggregate.
+type transaction struct {
+	line   int
+	fields map[string]string
+}
+
+func (t *transaction) parse(defaultCurrency string) (domain.Transaction, *statement.RecordError) {
+	txn := domain.Transaction{
+		Id:              t.fields["FITID"],
+		CounterpartName: t.fields["NAME"],
+		Description:     t.fields["MEMO"],
+		Currency:        strings.ToUpper(defaultCurrency),
+	}
+	if cur := t.fields["CURSYM"]; cur != "" {
+		txn.Currency = strings.ToUpper(cur)
+	}
+	if txn.CounterpartName == "" {
+		txn.CounterpartName = t.fields["PAYEEID"]
+	}
+
+	var err error
+	if txn.BookingDate, err = parseDate(t.fields["DTPOSTED"]); err != nil {
+		return txn, t.error("DTPOSTED", err)
+	}
+	if v := t.fields["DTUSER"]; v != "" {
+		if txn.Date, err = parseDate(v); err != nil {
+			return txn, t.error("DTUSER", err)
+		}
+	} else {
+		txn.Date = txn.BookingDate
+	}
+	if v := t.fields["DTAVAIL"]; v != "" {
+		if txn.ValueDate, err = parseDate(v); err != nil {
+			return txn, t.error("DTAVAIL", err)
+		}
+	}
+
+	amount := t.fields["TRNAMT"]
+	parser := money.Parser{DecimalSeparator: '.', Exponent: money.Exponent(txn.Currency)}
+	if strings.Contains(amount, ",") && !strings.Contains(amount, ".") {
+		// Some banks use a comma as decimal separator, which the
+		// specification allows.
+		parser.DecimalSeparator = ','
+	}
+	if txn.Amount, err = parser.Parse(amount); err != nil {
+		return txn, t.error("TRNAMT", err)
+	}
+	return txn, nil
+}
+
+func (t *transaction) error(element string, err error) *statement.RecordError {
+	return &statement.RecordError{Line: t.line, Column: element, Value: t.fields[element], Err: err}
+}
+
+type tokenKind int
+
+const (
+	tokenStart tokenKind = iota
+	tokenEnd
+	tokenText
+)
+
+type token struct {
+	kind  tokenKind
+	value string // Upper case element name, or trimmed and unescaped text.
+	line  int
+}
+
+// tokenizer splits OFX into element tags and text. It handles both SGML,
+// where leaf elements have no end tag, and XML. The OFX 1.x header and XML
+// processing instructions are skipped.
+type tokenizer struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newTokenizer(r io.Reader) *tokenizer {
+	return &tokenizer{r: bufio.NewReader(r), line: 1}
+}
+
+func (t *tokenizer) next() (token, error) {
+	for {
+		line := t.line
+		c, err := t.readByte()
+		if err != nil {
+			return token{}, err
+		}
+
+		if c == '<' {
+			tag, err := t.readUntil('>')
+			if err != nil {
+				return token{}, err
+			}
+			tag = strings.TrimSpace(tag)
+			switch {
+			case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+				continue
+			case strings.HasPrefix(tag, "/"):
+				return token{kind: tokenEnd, value: strings.ToUpper(strings.TrimSpace(tag[1:])), line: line}, nil
+			case strings.HasSuffix(tag, "/"):
+				// An empty XML element has no value.
+				continue
+			default:
+				name, _, _ := strings.Cut(tag, " ")
+				return token{kind: tokenStart, value: strings.ToUpper(name), line: line}, nil
+			}
+		}
+
+		text, err := t.readUntil('<')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return token{}, err
+		}
+		if !errors.Is(err, io.EOF) {
+			if err := t.unreadByte(); err != nil {
+				return token{}, err
+			}
+		}
+		text = strings.TrimSpace(string(c) + text)
+		if text != "" {
+			return token{kind: tokenText, value: unescape(text), line: line}, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return token{}, io.EOF
+		}
+	}
+}
+
+func (t *tokenizer) readByte() (byte, error) {
+	c, err := t.r.ReadByte()
+	if c == '\n' {
+		t.line++
+	}
+	return c, err
+}
+
+func (t *tokenizer) unreadByte() error {
+	return t.r.UnreadByte()
+}
+
+// readUntil reads up to, and including, delim, and returns what was read
+// without delim.
+func (t *tokenizer) readUntil(delim byte) (string, error) {
+	s, err := t.r.ReadString(delim)
+	t.line += strings.Count(s, "\n")
+	if err != nil {
+		return s, err
+	}
+	return s[:len(s)-1], nil
+}
+
+var unescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
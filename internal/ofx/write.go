@@ -0,0 +1,312 @@
+package ofx
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxNameLength is the maximum length of the NAME element of a transaction.
+const maxNameLength = 32
+
+// Writer writes transactions as an OFX 2.2 bank statement. It implements
+// [statement.Writer].
+type Writer struct {
+	// BankId and AccountId identify the account in BANKACCTFROM. An empty
+	// AccountId is taken from the first transaction with an Account, or from
+	// the summaries. OFX requires both, so writing fails if either is
+	// unknown.
+	BankId, AccountId string
+
+	// Summaries, if set, returns the summaries of the statements read, such
+	// as [statement.Summarizer.Summaries] of the reader. It is called once
+	// all transactions are read.
+	Summaries func() []statement.Summary
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// SetAccount sets the BankId and AccountId that are not empty.
+func (w *Writer) SetAccount(bankId, accountId string) {
+	w.BankId = cmp.Or(bankId, w.BankId)
+	w.AccountId = cmp.Or(accountId, w.AccountId)
+}
+
+// SetSummaries sets the Summaries of w.
+func (w *Writer) SetSummaries(summaries func() []statement.Summary) {
+	w.Summaries = summaries
+}
+
+// WriteAll writes txns as a single statement. The transactions are buffered
+// until txns is done, since the statement period and default currency come
+// before them in OFX.
+//
+// The default currency (CURDEF) of the statement is the currency of the first
+// transaction that has one, and transactions in other currencies get a
+// CURRENCY aggregate. Its exchange rate (CURRATE) is left out, since the
+// transactions do not tell it.
+//
+// The balance (LEDGERBAL) is the closing balance of the last summary that has
+// one in CURDEF. Without it, the balance is the sum of the transactions in
+// CURDEF, as if the account was empty before the first of them. Transactions without an Id get a FITID derived from
+// their content, so the same statement gets the same FITIDs every time.
+func (w *Writer) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	var all []domain.Transaction
+	for txn, err := range txns {
+		if err != nil {
+			return err
+		}
+		all = append(all, txn)
+	}
+
+	curdef := "XXX"
+	for _, txn := range all {
+		if txn.Currency != "" {
+			curdef = strings.ToUpper(txn.Currency)
+			break
+		}
+	}
+
+	var summaries []statement.Summary
+	if w.Summaries != nil {
+		summaries = w.Summaries()
+	}
+
+	accountId := w.AccountId
+	for _, txn := range all {
+		if accountId != "" {
+			break
+		}
+		accountId = txn.Account
+	}
+	for _, summary := range summaries {
+		if accountId != "" {
+			break
+		}
+		accountId = summary.Account
+	}
+	if accountId == "" {
+		return errors.New("the account of the statement is unknown, and is required by OFX")
+	}
+	if w.BankId == "" {
+		return errors.New("the bank of the statement is unknown, and is required by OFX")
+	}
+
+	var start, end time.Time
+	for i, txn := range all {
+		date := postedDate(txn)
+		if i == 0 || date.Before(start) {
+			start = date
+		}
+		if i == 0 || date.After(end) {
+			end = date
+		}
+	}
+	if len(all) == 0 {
+		start = time.Unix(0, 0).UTC()
+		end = start
+	}
+
+	out := bufio.NewWriter(target)
+	e := &elementWriter{w: out}
+
+	fmt.Fprint(out, "<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	fmt.Fprint(out, "<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	e.open("OFX")
+	e.open("SIGNONMSGSRSV1")
+	e.open("SONRS")
+	writeStatus(e)
+	e.leaf("DTSERVER", formatDate(end))
+	e.leaf("LANGUAGE", "ENG")
+	e.close("SONRS")
+	e.close("SIGNONMSGSRSV1")
+
+	e.open("BANKMSGSRSV1")
+	e.open("STMTTRNRS")
+	e.leaf("TRNUID", "0")
+	writeStatus(e)
+	e.open("STMTRS")
+	e.leaf("CURDEF", curdef)
+	e.open("BANKACCTFROM")
+	e.leaf("BANKID", w.BankId)
+	e.leaf("ACCTID", accountId)
+	e.leaf("ACCTTYPE", "CHECKING")
+	e.close("BANKACCTFROM")
+
+	e.open("BANKTRANLIST")
+	e.leaf("DTSTART", formatDate(start))
+	e.leaf("DTEND", formatDate(end))
+	fitids := map[string]int{}
+	for _, txn := range all {
+		writeTransaction(e, txn, curdef, fitids)
+	}
+	e.close("BANKTRANLIST")
+
+	balance, asOf := closingBalance(all, summaries, curdef)
+	if asOf.IsZero() {
+		asOf = end
+	}
+	e.open("LEDGERBAL")
+	e.leaf("BALAMT", money.Format(balance, money.Exponent(curdef), '.'))
+	e.leaf("DTASOF", formatDate(asOf))
+	e.close("LEDGERBAL")
+	e.close("STMTRS")
+	e.close("STMTTRNRS")
+	e.close("BANKMSGSRSV1")
+	e.close("OFX")
+
+	if e.err != nil {
+		return fmt.Errorf("failed to write OFX: %v", e.err)
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write OFX: %v", err)
+	}
+	return nil
+}
+
+func writeStatus(e *elementWriter) {
+	e.open("STATUS")
+	e.leaf("CODE", "0")
+	e.leaf("SEVERITY", "INFO")
+	e.close("STATUS")
+}
+
+func writeTransaction(e *elementWriter, txn domain.Transaction, curdef string, fitids map[string]int) {
+	currency := strings.ToUpper(txn.Currency)
+	if currency == "" {
+		currency = curdef
+	}
+
+	trntype := "CREDIT"
+	if txn.Amount < 0 {
+		trntype = "DEBIT"
+	}
+
+	fitid := txn.Id
+	if fitid == "" {
+		fitid = generateFitId(txn)
+	}
+	// FITIDs must be unique within the statement.
+	fitids[fitid]++
+	if n := fitids[fitid]; n > 1 {
+		fitid += "-" + strconv.Itoa(n)
+	}
+
+	e.open("STMTTRN")
+	e.leaf("TRNTYPE", trntype)
+	e.leaf("DTPOSTED", formatDate(postedDate(txn)))
+	if !txn.Date.IsZero() && !txn.Date.Equal(postedDate(txn)) {
+		e.leaf("DTUSER", formatDate(txn.Date))
+	}
+	if !txn.ValueDate.IsZero() {
+		e.leaf("DTAVAIL", formatDate(txn.ValueDate))
+	}
+	e.leaf("TRNAMT", money.Format(txn.Amount, money.Exponent(currency), '.'))
+	e.leaf("FITID", fitid)
+	if name := truncate(txn.CounterpartName, maxNameLength); name != "" {
+		e.leaf("NAME", name)
+	}
+	if txn.Description != "" {
+		e.leaf("MEMO", txn.Description)
+	}
+	if currency != curdef {
+		e.open("CURRENCY")
+		e.leaf("CURSYM", currency)
+		e.close("CURRENCY")
+	}
+	e.close("STMTTRN")
+}
+
+// postedDate returns the date the transaction was posted to the account.
+func postedDate(txn domain.Transaction) time.Time {
+	if !txn.BookingDate.IsZero() {
+		return txn.BookingDate
+	}
+	return txn.Date
+}
+
+// generateFitId returns an identifier derived from the date, amount, payee and
+// description of txn.
+func generateFitId(txn domain.Transaction) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s",
+		postedDate(txn).Format(time.DateOnly), txn.Amount, txn.Currency, txn.CounterpartName, txn.Description)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > n {
+		return strings.TrimSpace(string(runes[:n]))
+	}
+	return s
+}
+
+// closingBalance returns the closing balance in curdef of the last summary
+// that has one, and its date. Without one, it returns the sum of the
+// transactions in curdef and a zero date.
+func closingBalance(txns []domain.Transaction, summaries []statement.Summary, curdef string) (int, time.Time) {
+	for _, summary := range slices.Backward(summaries) {
+		closing := summary.Closing
+		if closing != nil && strings.EqualFold(cmp.Or(closing.Currency, summary.Currency, curdef), curdef) {
+			return closing.Amount, closing.Date
+		}
+	}
+	sum := 0
+	for _, txn := range txns {
+		if strings.EqualFold(cmp.Or(txn.Currency, curdef), curdef) {
+			sum += txn.Amount
+		}
+	}
+	return sum, time.Time{}
+}
+
+// elementWriter writes indented XML elements, and keeps the first error.
+type elementWriter struct {
+	w     io.Writer
+	depth int
+	err   error
+}
+
+func (e *elementWriter) open(name string) {
+	e.printf("%s<%s>\n", e.indent(), name)
+	e.depth++
+}
+
+func (e *elementWriter) close(name string) {
+	e.depth--
+	e.printf("%s</%s>\n", e.indent(), name)
+}
+
+func (e *elementWriter) leaf(name, value string) {
+	var escaped strings.Builder
+	// Writing to a strings.Builder does not fail.
+	_ = xml.EscapeText(&escaped, []byte(value))
+	e.printf("%s<%s>%s</%s>\n", e.indent(), name, escaped.String(), name)
+}
+
+func (e *elementWriter) indent() string {
+	return strings.Repeat("  ", e.depth)
+}
+
+func (e *elementWriter) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
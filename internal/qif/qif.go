@@ -0,0 +1,105 @@
+// Package qif reads and writes bank statements in the Quicken Interchange
+// Format.
+//
+// A QIF file is a list of records of one-letter fields, each on its own line
+// and ended by a '^' line. The records follow a "!Type:" line that tells what
+// they are. Only the bank-like types (Bank, Cash, CCard, Oth A and Oth L) are
+// statements of transactions; records of other types are skipped.
+package qif
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of day, month and year in a QIF date. QIF does not
+// say which is used, and programs write dates like "01/02/2025" both as
+// month/day and as day/month depending on where they are sold.
+type DateOrder int
+
+const (
+	// MonthFirst is month/day/year, as written by the US versions of Quicken
+	// and Microsoft Money.
+	MonthFirst DateOrder = iota
+	// DayFirst is day/month/year, as written by most European programs.
+	DayFirst
+)
+
+// ParseDateOrder parses a date order, which is "mdy" or "dmy".
+func ParseDateOrder(s string) (DateOrder, error) {
+	switch strings.ToLower(s) {
+	case "mdy", "":
+		return MonthFirst, nil
+	case "dmy":
+		return DayFirst, nil
+	}
+	return 0, fmt.Errorf("invalid QIF date order '%s': must be mdy or dmy", s)
+}
+
+func (o DateOrder) String() string {
+	if o == DayFirst {
+		return "dmy"
+	}
+	return "mdy"
+}
+
+// bankTypes are the account types whose records are transactions.
+var bankTypes = []string{"bank", "cash", "ccard", "oth a", "oth l"}
+
+// parseDate parses a QIF date such as "1/ 2'25", "01/02/2025" or
+// "2025-01-02". Dates that start with a four digit year are always read as
+// year, month, day. Two digit years are in the 2000s when written after an
+// apostrophe, as Quicken does, and otherwise in 1970-2069.
+func parseDate(value string, order DateOrder) (time.Time, error) {
+	s := strings.ReplaceAll(value, " ", "")
+	apostrophe := strings.Contains(s, "'")
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse date '%s'", value)
+		}
+		nums[i] = n
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = nums[0], nums[1], nums[2]
+	case order == DayFirst:
+		day, month, year = nums[0], nums[1], nums[2]
+	default:
+		month, day, year = nums[0], nums[1], nums[2]
+	}
+	if len(parts[0]) != 4 && len(parts[2]) <= 2 {
+		switch {
+		case apostrophe, year < 70:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || int(t.Month()) != month {
+		return time.Time{}, fmt.Errorf("could not parse date '%s' as %s", value, order)
+	}
+	return t, nil
+}
+
+// formatDate formats t with a four digit year in the given order.
+func formatDate(t time.Time, order DateOrder) string {
+	if order == DayFirst {
+		return t.Format("02/01/2006")
+	}
+	return t.Format("01/02/2006")
+}
@@ -0,0 +1,158 @@
+package qif_test
+
+import (
+	"bytes"
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/qif"
+	"fincli/internal/statement"
+	"fincli/internal/statement/statementtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const bankStatement = `!Account
+NChecking
+TBank
+^
+!Type:Bank
+D1/ 2'25
+T-1,234.56
+PStore
+MGroceries and
+Mhousehold
+LFood:Groceries
+N1001
+^
+D12/31/99
+U500.00
+PEmployer
+SSalary
+$500.00
+^
+!Type:Invst
+D1/3'25
+NBuy
+T100.00
+^
+`
+
+func TestReader_Bank(t *testing.T) {
+	want := []domain.Transaction{
+		{
+			Id:              "1001",
+			Date:            time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+			CounterpartName: "Store",
+			Description:     "Groceries and household",
+			Category:        "Food:Groceries",
+			Amount:          -123456,
+		},
+		{
+			Date:            time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC),
+			CounterpartName: "Employer",
+			Amount:          50000,
+		},
+	}
+
+	got := statementtest.Collect(t, qif.NewReader(qif.MonthFirst).All(strings.NewReader(bankStatement)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n\t%+v\nwant:\n\t%+v", got, want)
+	}
+}
+
+func TestReader_DateOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  qif.DateOrder
+		date   string
+		amount string
+		want   time.Time
+		wantN  int
+	}{
+		{"month first", qif.MonthFirst, "01/02/2025", "1.50", time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), 150},
+		{"day first", qif.DayFirst, "01/02/2025", "1,50", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), 150},
+		{"day first with dots", qif.DayFirst, "31.12.2024", "-1.234,50", time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), -123450},
+		{"year first", qif.DayFirst, "2025-01-02", "1,234", time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), 123400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "!Type:Bank\nD" + tt.date + "\nT" + tt.amount + "\n^\n"
+			got := statementtest.Collect(t, qif.NewReader(tt.order).All(strings.NewReader(data)))
+			if len(got) != 1 {
+				t.Fatalf("expected 1 transaction, got %d", len(got))
+			}
+			if !got[0].Date.Equal(tt.want) {
+				t.Errorf("date: got %v, want %v", got[0].Date, tt.want)
+			}
+			if got[0].Amount != tt.wantN {
+				t.Errorf("amount: got %d, want %d", got[0].Amount, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestReader_RecordError(t *testing.T) {
+	data := "!Type:Bank\nD13/01/2025\nT1.00\n^\nD01/13/2025\nT2.00\n^\n"
+
+	reader := qif.NewReader(qif.MonthFirst)
+	reader.SetFileName("export.qif")
+	var (
+		txns   []domain.Transaction
+		recErr *statement.RecordError
+	)
+	for txn, err := range reader.All(strings.NewReader(data)) {
+		if err != nil {
+			if !errors.As(err, &recErr) {
+				t.Fatalf("expected *statement.RecordError, got %v", err)
+			}
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	if recErr == nil || recErr.File != "export.qif" || recErr.Line != 2 || recErr.Column != "D" {
+		t.Errorf("unexpected error: %v", recErr)
+	}
+	if len(txns) != 1 || txns[0].Amount != 200 {
+		t.Errorf("expected reading to continue after the error, got %+v", txns)
+	}
+}
+
+func TestReader_Detect(t *testing.T) {
+	reader := qif.NewReader(qif.MonthFirst)
+	if !reader.Detect([]byte(bankStatement)) {
+		t.Error("expected QIF to be detected")
+	}
+	if reader.Detect([]byte("Date,Payee\n")) {
+		t.Error("expected CSV not to be detected")
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	for _, order := range []qif.DateOrder{qif.MonthFirst, qif.DayFirst} {
+		t.Run(order.String(), func(t *testing.T) {
+			want := statementtest.Collect(t, qif.NewReader(qif.MonthFirst).All(strings.NewReader(bankStatement)))
+
+			var buf bytes.Buffer
+			err := qif.NewWriter(order).WriteAll(&buf, statementtest.Seq(want))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := statementtest.Collect(t, qif.NewReader(order).All(strings.NewReader(buf.String())))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got:\n\t%+v\nwant:\n\t%+v\noutput:\n%s", got, want, buf.String())
+			}
+		})
+	}
+}
+
+func TestParseDateOrder(t *testing.T) {
+	if order, err := qif.ParseDateOrder("DMY"); err != nil || order != qif.DayFirst {
+		t.Errorf("got %v, %v", order, err)
+	}
+	if _, err := qif.ParseDateOrder("ymd"); err == nil {
+		t.Error("expected error for unsupported date order")
+	}
+}
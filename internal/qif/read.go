@@ -0,0 +1,188 @@
+package qif
+
+import (
+	"bufio"
+	"bytes"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Reader reads the transactions of QIF statements. It implements
+// [statement.Reader] and [statement.Detector].
+type Reader struct {
+	// DateOrder is the order of day and month in the dates of the file.
+	DateOrder DateOrder
+	// Currency is the currency of the amounts, which QIF does not record.
+	// It may be empty.
+	Currency string
+
+	fileName string
+}
+
+func NewReader(order DateOrder) *Reader {
+	return &Reader{DateOrder: order}
+}
+
+// SetFileName sets the name of the statement file that is reported in
+// errors.
+func (r *Reader) SetFileName(name string) {
+	r.fileName = name
+}
+
+// Detect reports whether sample starts with a QIF header line.
+func (r *Reader) Detect(sample []byte) bool {
+	sample = bytes.TrimPrefix(sample, []byte("\xef\xbb\xbf"))
+	sample = bytes.TrimLeft(sample, " \t\r\n")
+	header := bytes.ToLower(sample[:min(len(sample), 16)])
+	return bytes.HasPrefix(header, []byte("!type:")) ||
+		bytes.HasPrefix(header, []byte("!option:")) ||
+		bytes.HasPrefix(header, []byte("!account"))
+}
+
+// All returns an iterator over the transactions in the bank-like records of
+// source. Split lines (S, E and $) are ignored, so a split transaction is
+// read as one transaction with its total amount.
+func (r *Reader) All(source io.Reader) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		scanner := bufio.NewScanner(source)
+
+		// Files without a header are taken to be bank statements.
+		inBank := true
+		rec := &record{}
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			if lineNum == 1 {
+				line = strings.TrimPrefix(line, "\ufeff")
+			}
+			if line == "" {
+				continue
+			}
+
+			if line[0] == '!' {
+				header := strings.ToLower(line)
+				switch {
+				case strings.HasPrefix(header, "!type:"):
+					inBank = slices.Contains(bankTypes, strings.TrimSpace(header[len("!type:"):]))
+				case strings.HasPrefix(header, "!account"):
+					inBank = false
+				}
+				rec = &record{}
+				continue
+			}
+
+			if line[0] == '^' {
+				if inBank && !rec.empty() {
+					txn, recErr := rec.parse(r.DateOrder, r.Currency)
+					if recErr != nil {
+						recErr.File = r.fileName
+						if !yield(domain.Transaction{}, recErr) {
+							return
+						}
+					} else if !yield(txn, nil) {
+						return
+					}
+				}
+				rec = &record{}
+				continue
+			}
+
+			if rec.empty() {
+				rec.line = lineNum
+			}
+			rec.add(line[0], line[1:])
+		}
+		if err := scanner.Err(); err != nil {
+			yield(domain.Transaction{}, fmt.Errorf("could not read QIF: %w", err))
+		}
+	}
+}
+
+// record holds the fields of a QIF record by their code.
+type record struct {
+	line   int
+	fields map[byte]string
+	memo   []string
+}
+
+func (r *record) empty() bool {
+	return len(r.fields) == 0 && len(r.memo) == 0
+}
+
+func (r *record) add(code byte, value string) {
+	if r.fields == nil {
+		r.fields = map[byte]string{}
+	}
+	value = strings.TrimSpace(value)
+	switch code {
+	case 'M':
+		// Some programs write long memos over several M lines.
+		r.memo = append(r.memo, value)
+	case 'S', 'E', '$', '%':
+	default:
+		if _, ok := r.fields[code]; !ok {
+			r.fields[code] = value
+		}
+	}
+}
+
+func (r *record) parse(order DateOrder, currency string) (domain.Transaction, *statement.RecordError) {
+	txn := domain.Transaction{
+		Id:              r.fields['N'],
+		CounterpartName: r.fields['P'],
+		Description:     strings.Join(r.memo, " "),
+		Category:        r.fields['L'],
+		Currency:        strings.ToUpper(currency),
+	}
+
+	var err error
+	if txn.Date, err = parseDate(r.fields['D'], order); err != nil {
+		return txn, r.error('D', err)
+	}
+
+	code := byte('T')
+	if _, ok := r.fields[code]; !ok {
+		code = 'U'
+	}
+	if txn.Amount, err = parseAmount(r.fields[code], money.Exponent(currency)); err != nil {
+		return txn, r.error(code, err)
+	}
+	return txn, nil
+}
+
+func (r *record) error(code byte, err error) *statement.RecordError {
+	return &statement.RecordError{Line: r.line, Column: string(code), Value: r.fields[code], Err: err}
+}
+
+// parseAmount parses a QIF amount. QIF uses the decimal notation of the
+// locale of the program that wrote it, so the last '.' or ',' is taken as the
+// decimal separator, unless it is the only separator and is followed by
+// three digits, as in "1,234".
+func parseAmount(value string, exponent int) (int, error) {
+	parser := money.Parser{DecimalSeparator: '.', ThousandsSeparator: ',', Exponent: exponent}
+
+	i := strings.LastIndexAny(value, ".,")
+	if i >= 0 {
+		sep := value[i]
+		other := byte(',')
+		if sep == ',' {
+			other = '.'
+		}
+		digits := strings.TrimRight(value[i+1:], " -+)")
+		thousands := strings.Count(value, string(sep)) > 1 ||
+			(!strings.ContainsRune(value, rune(other)) && len(digits) == 3 && exponent < 3)
+		if thousands {
+			parser.DecimalSeparator, parser.ThousandsSeparator = rune(other), rune(sep)
+		} else {
+			parser.DecimalSeparator, parser.ThousandsSeparator = rune(sep), rune(other)
+		}
+	}
+	return parser.Parse(value)
+}
@@ -0,0 +1,63 @@
+package qif
+
+import (
+	"bufio"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// Writer writes transactions as a QIF bank statement. It implements
+// [statement.Writer].
+type Writer struct {
+	// DateOrder is the order of day and month in the written dates.
+	DateOrder DateOrder
+}
+
+func NewWriter(order DateOrder) *Writer {
+	return &Writer{DateOrder: order}
+}
+
+// WriteAll writes txns as "!Type:Bank" records with the fields D, T, P, M, L
+// and N. Amounts are written with '.' as decimal separator.
+func (w *Writer) WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error {
+	out := bufio.NewWriter(target)
+	fmt.Fprintln(out, "!Type:Bank")
+	for txn, err := range txns {
+		if err != nil {
+			return err
+		}
+		w.writeRecord(out, txn)
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write QIF: %v", err)
+	}
+	return nil
+}
+
+func (w *Writer) writeRecord(out io.Writer, txn domain.Transaction) {
+	date := txn.Date
+	if date.IsZero() {
+		date = txn.BookingDate
+	}
+	fmt.Fprintf(out, "D%s\n", formatDate(date, w.DateOrder))
+	fmt.Fprintf(out, "T%s\n", money.Format(txn.Amount, money.Exponent(txn.Currency), '.'))
+	writeField(out, 'P', txn.CounterpartName)
+	writeField(out, 'M', txn.Description)
+	writeField(out, 'L', txn.Category)
+	writeField(out, 'N', txn.Id)
+	fmt.Fprintln(out, "^")
+}
+
+// writeField writes a field line unless value is empty. Line breaks would end
+// the field, so they are replaced by spaces.
+func writeField(out io.Writer, code byte, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(out, "%c%s\n", code, value)
+}
@@ -0,0 +1,299 @@
+// Package rules enriches transactions by ordered rules, such as giving the
+// transactions whose description matches "VIPPS\*KIWI" the payee "Kiwi" and
+// the category "Groceries".
+//
+// Rules are written in YAML:
+//
+//	rules:
+//	  - name: Kiwi
+//	    match:
+//	      description: 'VIPPS\*KIWI|^KIWI'
+//	    set:
+//	      payee: Kiwi
+//	      category: Food:Groceries
+//	  - name: Large purchases
+//	    match:
+//	      max_amount: "-1000"
+//	    set:
+//	      tags: [review]
+//
+// Each rule that matches a transaction is applied, in order, so later rules
+// override the payee, category and memo set by earlier ones. A rule with
+// stop set is the last one applied to the transactions it matches.
+package rules
+
+import (
+	"errors"
+	"fincli/internal/domain"
+	"fincli/internal/money"
+	"fincli/internal/statement"
+	"fmt"
+	"iter"
+	"math/big"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule sets fields of the transactions it matches.
+type Rule struct {
+	// Name identifies the rule in errors.
+	Name  string `yaml:"name,omitempty"`
+	Match Match  `yaml:"match"`
+	Set   Set    `yaml:"set"`
+	// Stop makes the rule the last one applied to the transactions it
+	// matches.
+	Stop bool `yaml:"stop,omitempty"`
+}
+
+// Match is the condition of a rule. A transaction matches if it meets all
+// conditions that are set, so the empty Match matches all transactions.
+type Match struct {
+	// Description, Payee and Account are regular expressions that match the
+	// description, the counterpart name and the account of the transaction,
+	// ignoring case.
+	Description string `yaml:"description,omitempty"`
+	Payee       string `yaml:"payee,omitempty"`
+	Account     string `yaml:"account,omitempty"`
+
+	// MinAmount and MaxAmount bound the signed amount of the transaction,
+	// inclusive, as decimal numbers such as "-100.50". Money going out is
+	// negative.
+	MinAmount string `yaml:"min_amount,omitempty"`
+	MaxAmount string `yaml:"max_amount,omitempty"`
+
+	// From and To bound the date of the transaction, inclusive, as
+	// YYYY-MM-DD.
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"`
+}
+
+// Set holds the values a rule gives the transactions it matches. Empty
+// values leave the fields as they are.
+type Set struct {
+	Payee    string `yaml:"payee,omitempty"`
+	Category string `yaml:"category,omitempty"`
+	// Memo replaces the description of the transaction.
+	Memo string `yaml:"memo,omitempty"`
+	// Tags are added to the tags of the transaction.
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// Rules are compiled rules, ready to be applied to transactions.
+type Rules struct {
+	rules []compiledRule
+}
+
+type file struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Parse decodes and compiles the rules in the YAML document data.
+func Parse(data []byte) (*Rules, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("could not decode rules: %w", err)
+	}
+	return Compile(f.Rules)
+}
+
+// Load reads the rules in the YAML file at path.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rules: %w", err)
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Compile compiles rules, which are applied in order.
+func Compile(rules []Rule) (*Rules, error) {
+	compiled := make([]compiledRule, len(rules))
+	var errs []error
+	for i, rule := range rules {
+		c, err := rule.compile()
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			errs = append(errs, fmt.Errorf("rule %s: %w", name, err))
+			continue
+		}
+		compiled[i] = c
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &Rules{rules: compiled}, nil
+}
+
+// Apply applies the rules that match txn, and reports whether any did.
+func (r *Rules) Apply(txn *domain.Transaction) bool {
+	matched := false
+	for _, rule := range r.rules {
+		if !rule.matches(txn) {
+			continue
+		}
+		matched = true
+		rule.apply(txn)
+		if rule.stop {
+			break
+		}
+	}
+	return matched
+}
+
+// Transform returns a [statement.Transform] that applies the rules to each
+// transaction. Errors are passed on as they are.
+func (r *Rules) Transform() statement.Transform {
+	return func(txns iter.Seq2[domain.Transaction, error]) iter.Seq2[domain.Transaction, error] {
+		return func(yield func(domain.Transaction, error) bool) {
+			for txn, err := range txns {
+				if err == nil {
+					txn.Tags = slices.Clone(txn.Tags)
+					r.Apply(&txn)
+				}
+				if !yield(txn, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+type compiledRule struct {
+	description, payee, account *regexp.Regexp
+	minAmount, maxAmount        *big.Rat
+	from, to                    time.Time
+	set                         Set
+	stop                        bool
+}
+
+func (rule Rule) compile() (compiledRule, error) {
+	c := compiledRule{set: rule.Set, stop: rule.Stop}
+
+	patterns := []struct {
+		name    string
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{"description", rule.Match.Description, &c.description},
+		{"payee", rule.Match.Payee, &c.payee},
+		{"account", rule.Match.Account, &c.account},
+	}
+	for _, p := range patterns {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p.pattern)
+		if err != nil {
+			return c, fmt.Errorf("invalid %s pattern: %w", p.name, err)
+		}
+		*p.re = re
+	}
+
+	amounts := []struct {
+		name  string
+		value string
+		rat   **big.Rat
+	}{
+		{"min_amount", rule.Match.MinAmount, &c.minAmount},
+		{"max_amount", rule.Match.MaxAmount, &c.maxAmount},
+	}
+	for _, a := range amounts {
+		if a.value == "" {
+			continue
+		}
+		rat, ok := new(big.Rat).SetString(a.value)
+		if !ok {
+			return c, fmt.Errorf("invalid %s '%s': must be a decimal number such as -100.50", a.name, a.value)
+		}
+		*a.rat = rat
+	}
+
+	dates := []struct {
+		name  string
+		value string
+		date  *time.Time
+	}{
+		{"from", rule.Match.From, &c.from},
+		{"to", rule.Match.To, &c.to},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, d.value)
+		if err != nil {
+			return c, fmt.Errorf("invalid %s date '%s': must be YYYY-MM-DD", d.name, d.value)
+		}
+		*d.date = date
+	}
+	return c, nil
+}
+
+func (c compiledRule) matches(txn *domain.Transaction) bool {
+	if c.description != nil && !c.description.MatchString(txn.Description) {
+		return false
+	}
+	if c.payee != nil && !c.payee.MatchString(txn.CounterpartName) {
+		return false
+	}
+	if c.account != nil && !c.account.MatchString(txn.Account) {
+		return false
+	}
+
+	if c.minAmount != nil || c.maxAmount != nil {
+		amount := amountRat(txn)
+		if c.minAmount != nil && amount.Cmp(c.minAmount) < 0 {
+			return false
+		}
+		if c.maxAmount != nil && amount.Cmp(c.maxAmount) > 0 {
+			return false
+		}
+	}
+
+	if !c.from.IsZero() || !c.to.IsZero() {
+		// The date is compared in the time zone of the transaction.
+		y, m, d := txn.Date.Date()
+		date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		if !c.from.IsZero() && date.Before(c.from) {
+			return false
+		}
+		if !c.to.IsZero() && date.After(c.to) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c compiledRule) apply(txn *domain.Transaction) {
+	if c.set.Payee != "" {
+		txn.CounterpartName = c.set.Payee
+	}
+	if c.set.Category != "" {
+		txn.Category = c.set.Category
+	}
+	if c.set.Memo != "" {
+		txn.Description = c.set.Memo
+	}
+	for _, tag := range c.set.Tags {
+		if !slices.Contains(txn.Tags, tag) {
+			txn.Tags = append(txn.Tags, tag)
+		}
+	}
+}
+
+// amountRat returns the amount of txn in major units of its currency.
+func amountRat(txn *domain.Transaction) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(money.Exponent(txn.Currency))), nil)
+	return new(big.Rat).SetFrac(big.NewInt(int64(txn.Amount)), scale)
+}
@@ -0,0 +1,141 @@
+package rules_test
+
+import (
+	"fincli/internal/domain"
+	"fincli/internal/rules"
+	"fincli/internal/statement/statementtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rulesYAML = `
+rules:
+  - name: Kiwi
+    match:
+      description: 'VIPPS\*KIWI|^KIWI'
+    set:
+      payee: Kiwi
+      category: Groceries
+  - name: Large purchases
+    match:
+      max_amount: "-1000"
+    set:
+      tags: [review]
+  - name: Vacation
+    match:
+      from: 2025-07-01
+      to: 2025-07-31
+    set:
+      tags: [vacation]
+    stop: true
+  - name: Savings account
+    match:
+      account: '^1234'
+    set:
+      memo: Savings
+`
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRules_Apply(t *testing.T) {
+	r, err := rules.Parse([]byte(rulesYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		txn  domain.Transaction
+		want domain.Transaction
+	}{
+		{
+			name: "payee and category",
+			txn:  domain.Transaction{Date: date(1, 2), Description: "VIPPS*KIWI 123 OSLO", Amount: -12050, Currency: "NOK"},
+			want: domain.Transaction{Date: date(1, 2), Description: "VIPPS*KIWI 123 OSLO", Amount: -12050, Currency: "NOK",
+				CounterpartName: "Kiwi", Category: "Groceries"},
+		},
+		{
+			name: "amount range",
+			txn:  domain.Transaction{Date: date(1, 2), Description: "kiwi majorstuen", Amount: -150000, Currency: "NOK"},
+			want: domain.Transaction{Date: date(1, 2), Description: "kiwi majorstuen", Amount: -150000, Currency: "NOK",
+				CounterpartName: "Kiwi", Category: "Groceries", Tags: []string{"review"}},
+		},
+		{
+			name: "amount in a currency without decimals",
+			txn:  domain.Transaction{Date: date(1, 2), Amount: -999, Currency: "JPY"},
+			want: domain.Transaction{Date: date(1, 2), Amount: -999, Currency: "JPY"},
+		},
+		{
+			name: "date range and stop",
+			txn:  domain.Transaction{Date: date(7, 31), Account: "1234.56.78901", Amount: -100},
+			want: domain.Transaction{Date: date(7, 31), Account: "1234.56.78901", Amount: -100, Tags: []string{"vacation"}},
+		},
+		{
+			name: "account",
+			txn:  domain.Transaction{Date: date(8, 1), Account: "1234.56.78901", Amount: 100},
+			want: domain.Transaction{Date: date(8, 1), Account: "1234.56.78901", Amount: 100, Description: "Savings"},
+		},
+		{
+			name: "no match",
+			txn:  domain.Transaction{Date: date(1, 2), Description: "Rema 1000", Amount: -100},
+			want: domain.Transaction{Date: date(1, 2), Description: "Rema 1000", Amount: -100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.txn
+			r.Apply(&got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\t%+v\nwant:\t%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRules_Transform(t *testing.T) {
+	r, err := rules.Parse([]byte(rulesYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tags := []string{"existing"}
+	txns := statementtest.Seq([]domain.Transaction{{Date: date(7, 1), Tags: tags[:1:1]}})
+	for txn, err := range r.Transform()(txns) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(txn.Tags, []string{"existing", "vacation"}) {
+			t.Errorf("got tags %v", txn.Tags)
+		}
+	}
+	if len(tags) != 1 {
+		t.Errorf("the tags of the input were changed: %v", tags)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	data := `
+rules:
+  - name: Bad pattern
+    match:
+      payee: '('
+  - match:
+      min_amount: ten
+  - match:
+      from: 01.01.2025
+`
+	_, err := rules.Parse([]byte(data))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"rule Bad pattern: invalid payee pattern", "rule #2: invalid min_amount", "rule #3: invalid from date"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package statement
+
+import (
+	"errors"
+	"fincli/internal/domain"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// RecordError describes a record in a statement that could not be parsed.
+//
+// Readers yield a RecordError for each bad record, and continue with the
+// next record if the consumer of the iterator keeps going.
+type RecordError struct {
+	File   string // Name of the statement file, if known.
+	Line   int    // Line in the file where the record starts, starting at 1.
+	Column string // Name of the column with the bad value, if known.
+	Value  string // The raw value that could not be parsed.
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "line %d", e.Line)
+	if e.Column != "" {
+		fmt.Fprintf(&b, ", column '%s', value '%s'", e.Column, e.Value)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// SkipRecordErrors returns a Transform that drops the records that could not
+// be parsed, and appends their errors to skipped. Other errors are passed on.
+func SkipRecordErrors(skipped *[]*RecordError) Transform {
+	return func(txns iter.Seq2[domain.Transaction, error]) iter.Seq2[domain.Transaction, error] {
+		return func(yield func(domain.Transaction, error) bool) {
+			for txn, err := range txns {
+				var recErr *RecordError
+				if errors.As(err, &recErr) {
+					*skipped = append(*skipped, recErr)
+					continue
+				}
+				if !yield(txn, err) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package statement defines the interfaces shared by the readers and
+// writers of the statement formats that finCLI converts between.
+//
+// Transactions flow from a [Reader], through any [Transform], to a [Writer]
+// as an iterator, so statements are processed one transaction at a time.
+package statement
+
+import (
+	"fincli/internal/domain"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"time"
+)
+
+// Reader reads the transactions in a statement.
+type Reader interface {
+	// All returns an iterator over the transactions in source. A record that
+	// cannot be parsed is yielded as a [*RecordError], and reading continues
+	// unless the consumer stops. Any other error is yielded last.
+	All(source io.Reader) iter.Seq2[domain.Transaction, error]
+}
+
+// Writer writes transactions as a statement.
+type Writer interface {
+	// WriteAll writes the transactions from txns to target. It stops at the
+	// first error yielded by txns and returns it.
+	WriteAll(target io.Writer, txns iter.Seq2[domain.Transaction, error]) error
+}
+
+// Detector is implemented by readers that can tell whether a sample of a
+// statement, holding its first lines, is in their format.
+type Detector interface {
+	Detect(sample []byte) bool
+}
+
+// Summarizer is implemented by readers of formats that carry information about
+// the statement as a whole, such as the opening and closing balances.
+type Summarizer interface {
+	// Summaries returns the summaries of the statements read by the last
+	// call to All. They are complete once the iteration is done.
+	Summaries() []Summary
+}
+
+// Summary is the statement-level information of a statement.
+type Summary struct {
+	Id       string // The identifier of the statement, if any.
+	Account  string // The account number, such as an IBAN.
+	Currency string // The currency of the account, if known.
+
+	// Opening and Closing are the booked balances at the start and end of
+	// the statement period, or nil if not given.
+	Opening, Closing *Balance
+}
+
+// Balance is the balance of an account on a date.
+type Balance struct {
+	Date     time.Time
+	Amount   int // In minor units of Currency, as in domain.Transaction.
+	Currency string
+}
+
+// Transform modifies a stream of transactions between reading and writing.
+type Transform func(iter.Seq2[domain.Transaction, error]) iter.Seq2[domain.Transaction, error]
+
+// Convert reads the statement in source with reader and writes it to target
+// with writer, applying transforms in order. Transactions are streamed from
+// source to target, so the statement is never held in memory as a whole,
+// unless the writer needs it to be.
+func Convert(source io.Reader, target io.Writer, reader Reader, writer Writer, transforms ...Transform) error {
+	txns := reader.All(source)
+	for _, transform := range transforms {
+		txns = transform(txns)
+	}
+	return writer.WriteAll(target, txns)
+}
+
+// Codec is a named statement format with a reader, a writer or both. Readers
+// and writers may keep state, such as the file name of the statement, so a
+// new one is made for each statement.
+type Codec struct {
+	Id          string
+	Description string
+	NewReader   func() Reader // Nil if the format cannot be read.
+	NewWriter   func() Writer // Nil if the format cannot be written.
+}
+
+// Registry holds the known codecs by their Id.
+type Registry map[string]Codec
+
+// Add registers codec under its Id.
+func (r Registry) Add(codec Codec) {
+	r[codec.Id] = codec
+}
+
+func (r Registry) Get(name string) (Codec, error) {
+	codec, ok := r[name]
+	if !ok {
+		return Codec{}, fmt.Errorf("format '%s' is unknown", name)
+	}
+	return codec, nil
+}
+
+// Detect returns the codec whose reader recognizes sample. Codecs are tried
+// in order of their Id.
+func (r Registry) Detect(sample []byte) (Codec, bool) {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		codec := r[id]
+		if codec.NewReader == nil {
+			continue
+		}
+		if detector, ok := codec.NewReader().(Detector); ok && detector.Detect(sample) {
+			return codec, true
+		}
+	}
+	return Codec{}, false
+}
@@ -0,0 +1,34 @@
+// Package statementtest provides helpers for testing the readers and writers
+// of the statement formats.
+package statementtest
+
+import (
+	"fincli/internal/domain"
+	"iter"
+	"testing"
+)
+
+// Seq returns an iterator over txns, as a reader would yield them.
+func Seq(txns []domain.Transaction) iter.Seq2[domain.Transaction, error] {
+	return func(yield func(domain.Transaction, error) bool) {
+		for _, txn := range txns {
+			if !yield(txn, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the transactions yielded by txns, and fails the test at the
+// first error.
+func Collect(t testing.TB, txns iter.Seq2[domain.Transaction, error]) []domain.Transaction {
+	t.Helper()
+	var all []domain.Transaction
+	for txn, err := range txns {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		all = append(all, txn)
+	}
+	return all
+}